@@ -0,0 +1,188 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/cpus"
+)
+
+// SetAffinity sets the CPU affinities of the specified IRQ by writing them in
+// list format to “/proc/irq/#/smp_affinity_list”. This usually requires
+// appropriate privileges. SetAffinity returns an error if the IRQ doesn't
+// exist, the affinities cannot be set, or the list of affinities is empty.
+func SetAffinity(irqnum uint, affinities cpus.List) error {
+	return setAffinity("", irqnum, affinities)
+}
+
+// setAffinity sets the CPU affinities of the specified IRQ, with the
+// “/proc/irq/#/” pseudo files located beneath the specified root.
+func setAffinity(root string, irqnum uint, affinities cpus.List) error {
+	if len(affinities) == 0 {
+		return fmt.Errorf("cannot set affinity of IRQ %d: empty CPU list", irqnum)
+	}
+	f, err := os.OpenFile(
+		root+procirqPath+strconv.FormatUint(uint64(irqnum), 10)+smpAffinityNode,
+		os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("cannot set affinity of IRQ %d: %w", irqnum, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(affinities.String()); err != nil {
+		return fmt.Errorf("cannot set affinity of IRQ %d: %w", irqnum, err)
+	}
+	return nil
+}
+
+// EqualAffinities reports whether the specified CPU affinities cover the same
+// set of CPUs, regardless of how their ranges are expressed, such as “0-1”
+// versus “0,1”. This allows detecting affinity drift, for instance, between
+// the configured and effective affinities of an IRQ.
+func EqualAffinities(a, b cpus.List) bool {
+	return slices.Equal(trimmedSet(a), trimmedSet(b))
+}
+
+// trimmedSet returns the CPU set for the specified list without any trailing
+// all-zero words, as the lengths of CPU sets depend on how they were built.
+func trimmedSet(l cpus.List) cpus.Set {
+	set := l.Set()
+	for len(set) > 0 && set[len(set)-1] == 0 {
+		set = set[:len(set)-1]
+	}
+	return set
+}
+
+// AffinitiesContain reports whether the specified CPU affinities contain the
+// specified CPU. In contrast to [cpus.List] methods, the ranges of the
+// affinities don't need to be ordered and may overlap.
+func AffinitiesContain(l cpus.List, cpu uint) bool {
+	for _, cpurange := range l {
+		if cpu >= cpurange[0] && cpu <= cpurange[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// AffinitiesCount returns the number of CPUs in the specified CPU affinities,
+// counting CPUs in overlapping ranges only once.
+func AffinitiesCount(l cpus.List) int {
+	count := 0
+	for _, cpurange := range mergedRanges(l) {
+		count += int(cpurange[1] - cpurange[0] + 1)
+	}
+	return count
+}
+
+// AllAffinityCPUs returns an iterator looping over the individual CPU numbers
+// in the specified CPU affinities, in ascending order and producing CPUs in
+// overlapping ranges only once.
+func AllAffinityCPUs(l cpus.List) iter.Seq[uint] {
+	return func(yield func(uint) bool) {
+		for _, cpurange := range mergedRanges(l) {
+			for cpu := cpurange[0]; ; cpu++ {
+				if !yield(cpu) {
+					return
+				}
+				if cpu == cpurange[1] {
+					break // don't overflow when the range ends at ^uint(0).
+				}
+			}
+		}
+	}
+}
+
+// mergedRanges returns a copy of the specified CPU list with its ranges sorted
+// in ascending order and overlapping as well as adjacent ranges merged.
+func mergedRanges(l cpus.List) cpus.List {
+	sorted := slices.SortedFunc(slices.Values(l), func(a, b [2]uint) int {
+		return cmp.Compare(a[0], b[0])
+	})
+	merged := sorted[:0]
+	for _, cpurange := range sorted {
+		if n := len(merged); n > 0 {
+			// Take care to not overflow when checking for adjacent ranges.
+			if last := merged[n-1][1]; cpurange[0] <= last || cpurange[0]-1 == last {
+				merged[n-1][1] = max(last, cpurange[1])
+				continue
+			}
+		}
+		merged = append(merged, cpurange)
+	}
+	return merged
+}
+
+// ParseCPUList parses the specified CPU list in the kernel's list format, such
+// as “1-3,42”, as used, for instance, by “/proc/irq/#/smp_affinity_list”, the
+// “isolcpus=” kernel command line parameter, and cpuset files. Trailing white
+// space, such as a final newline, is ignored and an empty list is valid. In
+// contrast to [cpus.NewList], ParseCPUList additionally rejects ranges with
+// their end before their start, and reports the position of any malformed
+// input.
+func ParseCPUList(s string) (cpus.List, error) {
+	text := strings.TrimRight(s, " \t\n")
+	l := cpus.List{}
+	pos := 0
+	for pos < len(text) {
+		if pos > 0 {
+			if text[pos] != ',' {
+				return nil, fmt.Errorf("malformed CPU list at position %d: expected ','", pos)
+			}
+			pos++
+		}
+		from, next, ok := parseListNumber(text, pos)
+		if !ok {
+			return nil, fmt.Errorf("malformed CPU list at position %d: expected CPU number", pos)
+		}
+		pos = next
+		to := from
+		if pos < len(text) && text[pos] == '-' {
+			pos++
+			to, next, ok = parseListNumber(text, pos)
+			if !ok {
+				return nil, fmt.Errorf("malformed CPU list at position %d: expected CPU number", pos)
+			}
+			if to < from {
+				return nil, fmt.Errorf("malformed CPU list at position %d: range end %d before start %d",
+					pos, to, from)
+			}
+			pos = next
+		}
+		l = append(l, [2]uint{from, to})
+	}
+	return l, nil
+}
+
+// parseListNumber parses the decimal number starting at the specified position
+// in the text, returning the number and the position following it. It reports
+// false if there are no digits at this position or the number overflows.
+func parseListNumber(text string, pos int) (uint, int, bool) {
+	end := pos
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	num, err := strconv.ParseUint(text[pos:end], 10, strconv.IntSize)
+	if err != nil {
+		return 0, pos, false
+	}
+	return uint(num), end, true
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/thediveo/cpus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("setting IRQ affinities", func() {
+
+	var root string
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		irqdir := filepath.Join(root, procirqPath, "42")
+		Expect(os.MkdirAll(irqdir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(irqdir, smpAffinityNode), []byte("0-63\n"), 0o644)).
+			To(Succeed())
+	})
+
+	It("writes affinities in list format", func() {
+		Expect(setAffinity(root, 42, Successful(cpus.NewList([]byte("1-3,42"))))).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(root, procirqPath, "42", smpAffinityNode))).To(
+			Equal([]byte("1-3,42")))
+	})
+
+	It("rejects empty affinities", func() {
+		Expect(setAffinity(root, 42, cpus.List{})).To(MatchError(
+			ContainSubstring("cannot set affinity of IRQ 42")))
+	})
+
+	It("reports non-existing IRQs", func() {
+		Expect(setAffinity(root, 666, Successful(cpus.NewList([]byte("1"))))).To(
+			MatchError(fs.ErrNotExist))
+	})
+
+})
+
+var _ = Describe("parsing CPU lists", func() {
+
+	DescribeTable("valid CPU lists",
+		func(text string, expected cpus.List) {
+			Expect(ParseCPUList(text)).To(Equal(expected))
+		},
+		Entry("empty list", "", cpus.List{}),
+		Entry("single CPU", "42", cpus.List{{42, 42}}),
+		Entry("ranges and single CPUs", "1-3,42,60-63", cpus.List{{1, 3}, {42, 42}, {60, 63}}),
+		Entry("trailing newline", "0-1\n", cpus.List{{0, 1}}),
+	)
+
+	DescribeTable("malformed CPU lists",
+		func(text string, message string) {
+			_, err := ParseCPUList(text)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("non-number", "x", "position 0: expected CPU number"),
+		Entry("leading comma", ",1", "position 0: expected CPU number"),
+		Entry("trailing comma", "1,", "position 2: expected CPU number"),
+		Entry("missing range end", "1-", "position 2: expected CPU number"),
+		Entry("bad separator", "1-3;5", "position 3: expected ','"),
+		Entry("trailing garbage", "42x", "position 2: expected ','"),
+		Entry("reversed range", "0,3-1", "position 4: range end 1 before start 3"),
+		Entry("overflow", "99999999999999999999999", "position 0: expected CPU number"),
+	)
+
+})
+
+var _ = Describe("comparing CPU affinities", func() {
+
+	DescribeTable("equality of covered CPU sets",
+		func(a, b string, equal bool) {
+			al := Successful(ParseCPUList(a))
+			bl := Successful(ParseCPUList(b))
+			Expect(EqualAffinities(al, bl)).To(Equal(equal))
+			Expect(EqualAffinities(bl, al)).To(Equal(equal))
+		},
+		Entry("both empty", "", "", true),
+		Entry("identical", "1-3,42", "1-3,42", true),
+		Entry("range vs single CPUs", "0-1", "0,1", true),
+		Entry("split ranges", "0-7", "0-3,4-7", true),
+		Entry("overlapping ranges", "0-4,2-7", "0-7", true),
+		Entry("unordered", "42,1-3", "1-3,42", true),
+		Entry("empty vs non-empty", "", "0", false),
+		Entry("different CPUs", "0-1", "0,2", false),
+		Entry("superset", "0-63", "0-62", false),
+	)
+
+	It("handles nil affinities", func() {
+		Expect(EqualAffinities(nil, cpus.List{})).To(BeTrue())
+		Expect(EqualAffinities(nil, cpus.List{{0, 0}})).To(BeFalse())
+	})
+
+	DescribeTable("CPUs in affinities",
+		func(list string, count int, all []uint) {
+			l := Successful(ParseCPUList(list))
+			Expect(AffinitiesCount(l)).To(Equal(count))
+			Expect(slices.Collect(AllAffinityCPUs(l))).To(Equal(all))
+			for cpu := range uint(50) {
+				Expect(AffinitiesContain(l, cpu)).To(Equal(slices.Contains(all, cpu)), "CPU %d", cpu)
+			}
+		},
+		Entry("empty", "", 0, []uint(nil)),
+		Entry("single CPU", "0", 1, []uint{0}),
+		Entry("ranges", "1-3,42", 4, []uint{1, 2, 3, 42}),
+		Entry("overlapping ranges", "0-4,2-7", 8, []uint{0, 1, 2, 3, 4, 5, 6, 7}),
+		Entry("contained ranges", "5-9,6-7", 5, []uint{5, 6, 7, 8, 9}),
+		Entry("unordered", "42,1-3", 4, []uint{1, 2, 3, 42}),
+		Entry("unordered and adjacent", "4-7,0-3", 8, []uint{0, 1, 2, 3, 4, 5, 6, 7}),
+	)
+
+	It("handles CPUs at the upper end", func() {
+		l := cpus.List{{^uint(0) - 1, ^uint(0)}, {^uint(0), ^uint(0)}}
+		Expect(AffinitiesCount(l)).To(Equal(2))
+		Expect(slices.Collect(AllAffinityCPUs(l))).To(HaveExactElements(^uint(0)-1, ^uint(0)))
+		Expect(AffinitiesContain(l, ^uint(0))).To(BeTrue())
+		Expect(AffinitiesContain(nil, 0)).To(BeFalse())
+	})
+
+	It("stops the yield when told", func() {
+		items := 0
+		for range AllAffinityCPUs(cpus.List{{0, 100}}) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("doesn't modify the affinities", func() {
+		l := cpus.List{{4, 7}, {0, 5}}
+		_ = AffinitiesCount(l)
+		Expect(l).To(Equal(cpus.List{{4, 7}, {0, 5}}))
+	})
+
+})
@@ -0,0 +1,225 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"io"
+	"time"
+)
+
+// CollectInto reads the per-CPU counters of all (non-architecture-specific)
+// IRQs from “/proc/interrupts” directly into the caller-provided map, indexed
+// by IRQ number, and returns the list of CPUs currently online. In contrast
+// to ranging over [AllCounters] and cloning the counters, CollectInto reuses
+// the existing counter slices in the map where their capacity suffices, so
+// that repeatedly collecting into the same map avoids allocations.
+//
+// IRQs that have disappeared since a previous collection get removed from the
+// map. CollectInto returns an error if “/proc/interrupts” cannot be opened,
+// in particular [ErrUnsupportedPlatform] when not running on Linux.
+func CollectInto(dst map[uint][]uint64) (CPUList, error) {
+	return collectIntoFrom("", dst)
+}
+
+// collectIntoFrom collects the per-CPU counters into the specified map, with
+// “/proc/interrupts” located beneath the specified root.
+func collectIntoFrom(root string, dst map[uint][]uint64) (CPUList, error) {
+	f, err := openProcInterrupts(root)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return collectInto(f, dst), nil
+}
+
+// collectInto collects the per-CPU counters read from the specified reader in
+// “/proc/interrupts” format into the specified map, returning the list of
+// CPUs online.
+func collectInto(r io.Reader, dst map[uint][]uint64) CPUList {
+	// Mark all existing entries as stale by truncating their counter slices
+	// to zero length, while keeping their capacity for reuse. As IRQs always
+	// have at least a single counter, we later can identify the entries of
+	// disappeared IRQs by their zero length.
+	for irqnum, counters := range dst {
+		dst[irqnum] = counters[:0]
+	}
+	var cpus CPUList
+	iterateAllCounters(r, nil, func(irq IRQ) bool {
+		cpus = irq.CPUs
+		counters := dst[irq.Num]
+		if cap(counters) < len(irq.Counters) {
+			counters = make([]uint64, len(irq.Counters))
+		}
+		counters = counters[:len(irq.Counters)]
+		copy(counters, irq.Counters)
+		dst[irq.Num] = counters
+		return true
+	})
+	for irqnum, counters := range dst {
+		if len(counters) == 0 {
+			delete(dst, irqnum)
+		}
+	}
+	return cpus
+}
+
+// CollectMap reads the per-CPU counters of all (non-architecture-specific)
+// IRQs from “/proc/interrupts” and returns them as a map indexed by IRQ
+// number, together with the list of CPUs currently online. In contrast to the
+// transient IRQs produced by [AllCounters], the IRQs in the map own their
+// counters and thus can be safely retained and randomly accessed.
+//
+// CollectMap returns an error if “/proc/interrupts” cannot be opened, in
+// particular [ErrUnsupportedPlatform] when not running on Linux.
+func CollectMap() (map[uint]IRQ, CPUList, error) {
+	return collectMapFrom("")
+}
+
+// collectMapFrom returns the map of IRQs with their per-CPU counters, with
+// “/proc/interrupts” located beneath the specified root.
+func collectMapFrom(root string) (map[uint]IRQ, CPUList, error) {
+	f, err := openProcInterrupts(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	irqs, cpus := collectMap(f)
+	return irqs, cpus, nil
+}
+
+// collectMap returns the map of IRQs with their per-CPU counters read from the
+// specified reader in “/proc/interrupts” format, as well as the list of CPUs
+// online.
+func collectMap(r io.Reader) (map[uint]IRQ, CPUList) {
+	irqs := map[uint]IRQ{}
+	var cpus CPUList
+	iterateAllCounters(r, nil, func(irq IRQ) bool {
+		cpus = irq.CPUs
+		irqs[irq.Num] = irq.Clone()
+		return true
+	})
+	return irqs, cpus
+}
+
+// InterruptsOnCPUs returns the total number of interrupts of all
+// (non-architecture-specific) IRQs on the specified CPUs, as well as the
+// per-IRQ numbers of interrupts on these CPUs, such as for checking that
+// isolated CPUs are indeed mostly free of interrupts. The per-IRQ map only
+// contains IRQs that had interrupts on the specified CPUs. Specified CPUs that
+// are currently offline are ignored.
+//
+// InterruptsOnCPUs returns an error if “/proc/interrupts” cannot be opened, in
+// particular [ErrUnsupportedPlatform] when not running on Linux.
+func InterruptsOnCPUs(cpus CPUList) (total uint64, perIRQ map[uint]uint64, err error) {
+	return interruptsOnCPUsFrom("", cpus)
+}
+
+// interruptsOnCPUsFrom returns the total and per-IRQ numbers of interrupts on
+// the specified CPUs, with “/proc/interrupts” located beneath the specified
+// root.
+func interruptsOnCPUsFrom(root string, cpus CPUList) (uint64, map[uint]uint64, error) {
+	f, err := openProcInterrupts(root)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	total, perIRQ := interruptsOnCPUs(f, cpus)
+	return total, perIRQ, nil
+}
+
+// interruptsOnCPUs returns the total and per-IRQ numbers of interrupts on the
+// specified CPUs, read from the specified reader in “/proc/interrupts” format.
+func interruptsOnCPUs(r io.Reader, cpus CPUList) (uint64, map[uint]uint64) {
+	var total uint64
+	perIRQ := map[uint]uint64{}
+	var indices []int
+	iterateAllCounters(r, nil, func(irq IRQ) bool {
+		// Resolve the CPUs to their counter indices only once, as the CPUs
+		// online are the same for all IRQs.
+		if indices == nil {
+			indices = make([]int, 0, len(cpus))
+			for _, cpu := range cpus {
+				if idx, ok := irq.CPUs.Index(cpu); ok {
+					indices = append(indices, idx)
+				}
+			}
+		}
+		var count uint64
+		for _, idx := range indices {
+			count += irq.Counters[idx]
+		}
+		if count != 0 {
+			perIRQ[irq.Num] = count
+			total += count
+		}
+		return true
+	})
+	return total, perIRQ
+}
+
+// Collector repeatedly collects Snapshots of the per-CPU counters of all
+// (non-architecture-specific) IRQs, reusing its own buffers across
+// collections in order to avoid allocations. The zero value of a Collector is
+// ready to use.
+//
+// A Collector is not safe for concurrent use: Collect must not be called from
+// multiple goroutines at the same time.
+type Collector struct {
+	root  string      // root of the file system to read "/proc/interrupts" from.
+	snaps [2]Snapshot // double-buffered Snapshots.
+	next  int         // index of the next Snapshot buffer to collect into.
+}
+
+// Collect collects a Snapshot of the per-CPU counters of all
+// (non-architecture-specific) IRQs. The returned Snapshot is owned by this
+// Collector and stays valid until the next but one Collect call. This way, the
+// Snapshot returned by the previous Collect call can still be used together
+// with the current one in order to calculate their [Snapshot.Delta].
+//
+// Collect returns an error if “/proc/interrupts” cannot be opened, in
+// particular [ErrUnsupportedPlatform] when not running on Linux.
+func (c *Collector) Collect() (*Snapshot, error) {
+	f, err := openProcInterrupts(c.root)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	snap := &c.snaps[c.next]
+	c.next = 1 - c.next
+	snap.Time = time.Now()
+	snap.CPUs = nil
+	irqs := snap.IRQs[:0]
+	iterateAllCounters(f, nil, func(irq IRQ) bool {
+		snap.CPUs = irq.CPUs
+		// Reuse the IRQ elements as well as their counter slices from a
+		// previous collection where possible.
+		if len(irqs) < cap(irqs) {
+			irqs = irqs[:len(irqs)+1]
+		} else {
+			irqs = append(irqs, IRQ{})
+		}
+		dst := &irqs[len(irqs)-1]
+		dst.Num = irq.Num
+		dst.CPUs = irq.CPUs
+		if cap(dst.Counters) < len(irq.Counters) {
+			dst.Counters = make([]uint64, len(irq.Counters))
+		}
+		dst.Counters = dst.Counters[:len(irq.Counters)]
+		copy(dst.Counters, irq.Counters)
+		return true
+	})
+	snap.IRQs = irqs
+	return snap, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("collecting counters into a map", func() {
+
+	It("collects into an empty map", func() {
+		counters := map[uint][]uint64{}
+		cpus := collectInto(strings.NewReader(procInterruptsText), counters)
+		Expect(cpus).To(HaveExactElements(uint(1), uint(42), uint(666)))
+		Expect(counters).To(Equal(map[uint][]uint64{
+			1: {2, 3, 4},
+			5: {6, 7, 8},
+		}))
+	})
+
+	It("reuses counter slices and removes disappeared IRQs", func() {
+		reused := make([]uint64, 3, 10)
+		counters := map[uint][]uint64{
+			1:   reused,
+			5:   {42},
+			666: {1, 2, 3},
+		}
+		cpus := collectInto(strings.NewReader(procInterruptsText), counters)
+		Expect(cpus).To(HaveLen(3))
+		Expect(counters).To(Equal(map[uint][]uint64{
+			1: {2, 3, 4},
+			5: {6, 7, 8},
+		}))
+		Expect(&counters[1][0]).To(BeIdenticalTo(&reused[0]))
+	})
+
+	It("empties the map when there are no IRQs", func() {
+		counters := map[uint][]uint64{1: {1}}
+		Expect(collectInto(strings.NewReader(""), counters)).To(BeEmpty())
+		Expect(counters).To(BeEmpty())
+	})
+
+	It("collects from files", func() {
+		counters := map[uint][]uint64{}
+		Expect(collectIntoFrom("./testdata/mixed", counters)).To(HaveLen(4))
+		Expect(counters).To(HaveLen(5))
+		Expect(counters).To(HaveKeyWithValue(uint(42), []uint64{0, 1234, 0, 56}))
+
+		_, err := collectIntoFrom("./testdata/non-existing", counters)
+		Expect(err).To(HaveOccurred())
+
+		Expect(Successful(CollectInto(counters))).NotTo(BeEmpty())
+		Expect(counters).NotTo(BeEmpty())
+	})
+
+})
+
+var _ = Describe("collecting counters as a map", func() {
+
+	It("returns a map of cloned IRQs", func() {
+		irqs, cpus := collectMap(strings.NewReader(procInterruptsText))
+		Expect(cpus).To(HaveExactElements(uint(1), uint(42), uint(666)))
+		Expect(irqs).To(HaveLen(2))
+		Expect(irqs).To(HaveKeyWithValue(uint(1), And(
+			HaveField("Num", uint(1)),
+			HaveField("Counters", HaveExactElements(uint64(2), uint64(3), uint64(4))))))
+		Expect(irqs).To(HaveKeyWithValue(uint(5),
+			HaveField("Counters", HaveExactElements(uint64(6), uint64(7), uint64(8)))))
+	})
+
+	It("returns an empty map when there are no IRQs", func() {
+		irqs, cpus := collectMap(strings.NewReader(""))
+		Expect(irqs).To(BeEmpty())
+		Expect(cpus).To(BeEmpty())
+	})
+
+	It("collects from files", func() {
+		irqs, cpus, err := collectMapFrom("./testdata/mixed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cpus).To(HaveLen(4))
+		Expect(irqs).To(HaveKeyWithValue(uint(43),
+			HaveField("Counters", HaveExactElements(uint64(666), uint64(0), uint64(0), uint64(0)))))
+
+		_, _, err = collectMapFrom("./testdata/non-existing")
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+
+		irqs, cpus, err = CollectMap()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(irqs).NotTo(BeEmpty())
+		Expect(cpus).NotTo(BeEmpty())
+	})
+
+})
+
+var _ = Describe("counting interrupts on CPUs", func() {
+
+	It("sums the interrupts on the specified CPUs", func() {
+		total, perIRQ := interruptsOnCPUs(strings.NewReader(procInterruptsText), CPUList{42, 666, 1000})
+		Expect(total).To(Equal(uint64(3 + 4 + 7 + 8)))
+		Expect(perIRQ).To(Equal(map[uint]uint64{1: 7, 5: 15}))
+
+		total, perIRQ = interruptsOnCPUs(strings.NewReader(procInterruptsText), CPUList{2})
+		Expect(total).To(BeZero())
+		Expect(perIRQ).To(BeEmpty())
+
+		total, perIRQ = interruptsOnCPUs(strings.NewReader(procInterruptsText), nil)
+		Expect(total).To(BeZero())
+		Expect(perIRQ).To(BeEmpty())
+	})
+
+	It("counts from files", func() {
+		total, perIRQ, err := interruptsOnCPUsFrom("./testdata/mixed", CPUList{1, 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(total).To(Equal(uint64(9 + 1 + 1234 + 56)))
+		Expect(perIRQ).To(Equal(map[uint]uint64{1: 9, 8: 1, 42: 1290}))
+
+		_, _, err = interruptsOnCPUsFrom("./testdata/non-existing", CPUList{0})
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+
+		cpus, ok := OnlineCPUs()
+		Expect(ok).To(BeTrue())
+		total, _, err = InterruptsOnCPUs(cpus)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(total).NotTo(BeZero())
+	})
+
+})
+
+var _ = Describe("collector", func() {
+
+	It("collects double-buffered snapshots", func() {
+		c := Collector{root: "./testdata/mixed"}
+		snap1 := Successful(c.Collect())
+		Expect(snap1.Time).NotTo(BeZero())
+		Expect(snap1.CPUs).To(HaveExactElements(uint(0), uint(1), uint(2), uint(3)))
+		Expect(snap1.IRQs).To(HaveLen(5))
+		Expect(snap1.IRQs[3].Num).To(Equal(uint(42)))
+		Expect(snap1.IRQs[3].Counters).To(HaveExactElements(
+			uint64(0), uint64(1234), uint64(0), uint64(56)))
+
+		snap2 := Successful(c.Collect())
+		Expect(snap2).NotTo(BeIdenticalTo(snap1))
+		Expect(snap1.Delta(*snap2)).To(HaveEach(
+			HaveField("Counters", HaveEach(BeZero()))))
+
+		counters := snap1.IRQs[0].Counters
+		snap3 := Successful(c.Collect())
+		Expect(snap3).To(BeIdenticalTo(snap1))
+		Expect(&snap3.IRQs[0].Counters[0]).To(BeIdenticalTo(&counters[0]))
+		Expect(snap3.IRQs).To(HaveLen(5))
+	})
+
+	It("reports errors", func() {
+		c := Collector{root: "./testdata/non-existing"}
+		_, err := c.Collect()
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+	})
+
+	It("collects from the system", func() {
+		var c Collector
+		snap := Successful(c.Collect())
+		Expect(snap.CPUs).NotTo(BeEmpty())
+		Expect(snap.IRQs).NotTo(BeEmpty())
+	})
+
+})
@@ -0,0 +1,73 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/thediveo/cpus"
+)
+
+const syscpuPresentPath = "/sys/devices/system/cpu/present"
+
+// AllCPUs returns the lists of CPUs currently online and offline. The online
+// CPUs are taken from the header line of “/proc/interrupts”, so these are
+// exactly the CPUs with counter columns. The total set of CPUs is taken from
+// “/sys/devices/system/cpu/present”, which lists all CPUs physically present
+// in the system, whether online or offline; the offline CPUs then are the
+// present CPUs not online.
+//
+// AllCPUs returns an error if either source cannot be read, in particular
+// [ErrUnsupportedPlatform] when not running on Linux.
+func AllCPUs() (online CPUList, offline CPUList, err error) {
+	return allCPUs("")
+}
+
+// allCPUs returns the lists of CPUs online and offline, with the pseudo files
+// located beneath the specified root.
+func allCPUs(root string) (CPUList, CPUList, error) {
+	f, err := openProcInterrupts(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	online, ok := onlineCPUsOf(f)
+	if !ok {
+		return nil, nil, errors.New("cannot determine online CPUs")
+	}
+	contents, err := os.ReadFile(root + syscpuPresentPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot determine present CPUs: %w", err)
+	}
+	line, ok := lineOf(contents)
+	if !ok {
+		return nil, nil, errors.New("cannot determine present CPUs: malformed list")
+	}
+	present, err := cpus.NewList(line)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot determine present CPUs: %w", err)
+	}
+	offline := CPUList{}
+	for _, cpurange := range present {
+		for cpu := cpurange[0]; cpu <= cpurange[1]; cpu++ {
+			if !online.Contains(cpu) {
+				offline = append(offline, cpu)
+			}
+		}
+	}
+	return online, offline, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("online and offline CPUs", func() {
+
+	It("returns online and offline CPUs", func() {
+		online, offline, err := allCPUs("./testdata/mixed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(online).To(HaveExactElements(uint(0), uint(1), uint(2), uint(3)))
+		Expect(offline).To(HaveExactElements(uint(4), uint(5)))
+	})
+
+	It("reports errors", func() {
+		_, _, err := allCPUs("./testdata/non-existing")
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+
+		_, _, err = allCPUs("./testdata/arm64")
+		Expect(err).To(MatchError(ContainSubstring("cannot determine present CPUs")))
+
+		root := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "proc"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, procInterruptsPath), []byte("foo\n"), 0o644)).
+			To(Succeed())
+		_, _, err = allCPUs(root)
+		Expect(err).To(MatchError(ContainSubstring("cannot determine online CPUs")))
+
+		Expect(os.WriteFile(filepath.Join(root, procInterruptsPath), []byte(procInterruptsText), 0o644)).
+			To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, filepath.Dir(syscpuPresentPath)), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, syscpuPresentPath), []byte("0-"), 0o644)).
+			To(Succeed())
+		_, _, err = allCPUs(root)
+		Expect(err).To(MatchError(ContainSubstring("malformed")))
+		Expect(os.WriteFile(filepath.Join(root, syscpuPresentPath), []byte("0-\n"), 0o644)).
+			To(Succeed())
+		_, _, err = allCPUs(root)
+		Expect(err).To(MatchError(ContainSubstring("cannot determine present CPUs")))
+	})
+
+	It("returns the system's CPUs", func() {
+		online, _, err := AllCPUs()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(online).NotTo(BeEmpty())
+	})
+
+})
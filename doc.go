@@ -64,8 +64,9 @@ on /sys/kernel/irq]:
   - “actions”: the IRQ action chain in form of a comma-separated list of zero or
     more actions associated with this interrupt. Actions might be device names,
     but also other elements, such as individual RX/TX queue IRQs of network cards.
-  - “chip_name”
-  - “hwirq”
+  - “chip_name”: the name of the IRQ chip handling this interrupt, such as
+    “IR-PCI-MSI-0000:00:14.0”.
+  - “hwirq”: the hardware IRQ number, local to its IRQ domain.
   - “name”: the clear-text name of the flow handler, such as “edge”, et cetera.
   - “per_cpu_count”: a list of comma-separated counters per CPU that currently is
     in the system, either online of offline. This field thus differs from
@@ -0,0 +1,85 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"iter"
+
+	"github.com/thediveo/faf"
+)
+
+const debugirqPath = "/sys/kernel/debug/irq/irqs/"
+
+// IRQDomains returns an iterator looping over the numbers of all IRQs together
+// with the names of the IRQ domains mapping their hardware IRQs to the Linux
+// IRQ numbers, in no particular order. IRQs without a domain are skipped.
+//
+// There's no dedicated sysfs pseudo file for the IRQ domain names, so
+// IRQDomains needs to resort to debugfs “/sys/kernel/debug/irq/irqs/#”. This
+// is available only when the kernel has been compiled with
+// CONFIG_GENERIC_IRQ_DEBUGFS, debugfs is mounted, and the caller is
+// sufficiently privileged; otherwise, the iterator doesn't produce anything.
+// In contrast, the “domain” column of “/proc/interrupts”, please see
+// [IRQInfo], only shows the hardware IRQ number within the domain.
+//
+// As debugfs pseudo files are rather expensive to read, [AllIRQDetails]
+// doesn't read them; callers have to explicitly opt in using IRQDomains.
+func IRQDomains() iter.Seq2[uint, string] {
+	return irqDomains("")
+}
+
+// irqDomains returns an iterator looping over the IRQ numbers and their domain
+// names, with the pseudo files located beneath the specified root.
+func irqDomains(root string) iter.Seq2[uint, string] {
+	return func(yield func(uint, string) bool) {
+		var contents []byte
+		for entry := range faf.ReadDir(root + debugirqPath) {
+			if entry.IsDir() {
+				continue
+			}
+			irqnum, ok := faf.ParseUint(entry.Name)
+			if !ok {
+				continue
+			}
+			contents, ok = faf.ReadFile(root+debugirqPath+string(entry.Name), contents)
+			if !ok {
+				continue
+			}
+			domain := debugIRQField(contents, "domain:")
+			if len(domain) == 0 {
+				continue
+			}
+			if !yield(uint(irqnum), string(domain)) {
+				return
+			}
+		}
+	}
+}
+
+// debugIRQField returns the value of the field with the specified name
+// (including its trailing colon) from the passed contents of a debugfs
+// “/sys/kernel/debug/irq/irqs/#” pseudo file, with surrounding spaces removed.
+// If the field isn't present, debugIRQField returns nil.
+func debugIRQField(contents []byte, name string) []byte {
+	for len(contents) > 0 {
+		line, rest, _ := bytes.Cut(contents, []byte{'\n'})
+		contents = rest
+		if value, ok := bytes.CutPrefix(line, []byte(name)); ok {
+			return bytes.TrimSpace(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"maps"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IRQ domains", func() {
+
+	It("finds debugfs fields", func() {
+		Expect(debugIRQField(nil, "domain:")).To(BeNil())
+		Expect(debugIRQField([]byte("node: 0\n"), "domain:")).To(BeNil())
+		Expect(debugIRQField([]byte("node: 0\ndomain:  FOO-1 \n hwirq: 0x1\n"), "domain:")).To(
+			Equal([]byte("FOO-1")))
+		Expect(debugIRQField([]byte("domain: BAR"), "domain:")).To(Equal([]byte("BAR")))
+	})
+
+	It("returns the domains of IRQs", func() {
+		Expect(maps.Collect(irqDomains("./testdata/mixed"))).To(Equal(map[uint]string{
+			42: "INTEL-IR-MSI-1-2",
+		}))
+		Expect(maps.Collect(irqDomains("./testdata/non-existing"))).To(BeEmpty())
+	})
+
+	It("stops the yield when told", func() {
+		items := 0
+		for range irqDomains("./testdata/mixed") {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+})
@@ -0,0 +1,130 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+
+	"github.com/thediveo/faf"
+)
+
+// skipSpace returns the passed text with any leading spaces removed.
+func skipSpace(b []byte) []byte {
+	for len(b) > 0 && b[0] == ' ' {
+		b = b[1:]
+	}
+	return b
+}
+
+// excessCounter reports whether the next field at the current position of the
+// specified bytestring is yet another (decimal) counter, consuming it.
+func excessCounter(bstr *faf.Bytestring) bool {
+	if bstr.SkipSpace() {
+		return false
+	}
+	if _, ok := bstr.Uint64(); !ok {
+		return false
+	}
+	ch, ok := bstr.Next()
+	return !ok || ch == ' '
+}
+
+// untab replaces any tabs in the passed text with spaces, in place.
+func untab(b []byte) {
+	for {
+		idx := bytes.IndexByte(b, '\t')
+		if idx < 0 {
+			return
+		}
+		b[idx] = ' '
+		b = b[idx+1:]
+	}
+}
+
+// nextField returns the next space-delimited field from the passed text,
+// skipping any leading spaces, as well as the remaining text following the
+// field. If there are no more fields, then the returned field is empty. The
+// returned field and remaining text are sub-slices of the passed text, so no
+// allocations are involved.
+func nextField(b []byte) (field, rest []byte) {
+	b = skipSpace(b)
+	end := 0
+	for end < len(b) && b[end] != ' ' {
+		end++
+	}
+	return b[:end], b[end:]
+}
+
+// parseInt returns the signed decimal integer value of the passed text,
+// reporting false if the text is not a valid signed decimal integer.
+func parseInt(b []byte) (int64, bool) {
+	neg := len(b) > 0 && b[0] == '-'
+	if neg {
+		b = b[1:]
+	}
+	u, ok := faf.ParseUint(b)
+	if !ok || u > 1<<63 || (!neg && u == 1<<63) {
+		return 0, false
+	}
+	if neg {
+		return -int64(u), true
+	}
+	return int64(u), true
+}
+
+// parseHex returns the unsigned hexadecimal integer value of the passed text,
+// reporting false if the text is empty, not hexadecimal, or overflows.
+func parseHex(b []byte) (uint64, bool) {
+	if len(b) == 0 || len(b) > 16 {
+		return 0, false
+	}
+	var val uint64
+	for _, ch := range b {
+		switch {
+		case ch >= '0' && ch <= '9':
+			ch -= '0'
+		case ch >= 'a' && ch <= 'f':
+			ch -= 'a' - 10
+		case ch >= 'A' && ch <= 'F':
+			ch -= 'A' - 10
+		default:
+			return 0, false
+		}
+		val = val<<4 | uint64(ch)
+	}
+	return val, true
+}
+
+// appendUint64CSV appends the comma-separated unsigned decimal numbers of the
+// passed text to dst, returning the extended slice. This allows callers to
+// reuse their buffers. appendUint64CSV reports false if any element is
+// malformed, including empty elements.
+func appendUint64CSV(dst []uint64, b []byte) ([]uint64, bool) {
+	for {
+		field := b
+		sep := bytes.IndexByte(b, ',')
+		if sep >= 0 {
+			field, b = b[:sep], b[sep+1:]
+		}
+		num, ok := faf.ParseUint(field)
+		if !ok {
+			return dst, false
+		}
+		dst = append(dst, num)
+		if sep < 0 {
+			return dst, true
+		}
+	}
+}
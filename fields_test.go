@@ -0,0 +1,118 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"math"
+
+	"github.com/thediveo/faf"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fields", func() {
+
+	It("skips leading spaces", func() {
+		Expect(skipSpace(nil)).To(BeEmpty())
+		Expect(skipSpace([]byte("   "))).To(BeEmpty())
+		Expect(string(skipSpace([]byte("  foo bar ")))).To(Equal("foo bar "))
+	})
+
+	It("replaces tabs with spaces", func() {
+		untab(nil)
+		b := []byte("\t1:\t2 \t3\t")
+		untab(b)
+		Expect(string(b)).To(Equal(" 1: 2  3 "))
+	})
+
+	It("detects excess counters", func() {
+		for _, text := range []string{" 42", "  42  foo", "1"} {
+			Expect(excessCounter(faf.NewBytestring([]byte(text)))).To(BeTrue(), "text %q", text)
+		}
+		for _, text := range []string{"", "  ", " foo", " 42-edge", " 42x"} {
+			Expect(excessCounter(faf.NewBytestring([]byte(text)))).To(BeFalse(), "text %q", text)
+		}
+	})
+
+	It("returns fields one after another", func() {
+		field, rest := nextField([]byte(""))
+		Expect(field).To(BeEmpty())
+		Expect(rest).To(BeEmpty())
+
+		field, rest = nextField([]byte("  foo  bar"))
+		Expect(string(field)).To(Equal("foo"))
+		Expect(string(rest)).To(Equal("  bar"))
+
+		field, rest = nextField(rest)
+		Expect(string(field)).To(Equal("bar"))
+		Expect(rest).To(BeEmpty())
+
+		field, _ = nextField(rest)
+		Expect(field).To(BeEmpty())
+	})
+
+	It("parses signed integers", func() {
+		for text, expected := range map[string]int64{
+			"42":                   42,
+			"-1":                   -1,
+			"-9223372036854775808": math.MinInt64,
+		} {
+			val, ok := parseInt([]byte(text))
+			Expect(ok).To(BeTrue(), "text %q", text)
+			Expect(val).To(Equal(expected), "text %q", text)
+		}
+		for _, text := range []string{"", "-", "--1", "abc", "9223372036854775808"} {
+			_, ok := parseInt([]byte(text))
+			Expect(ok).To(BeFalse(), "text %q", text)
+		}
+	})
+
+	It("parses hexadecimal integers", func() {
+		for text, expected := range map[string]uint64{
+			"0":                0,
+			"0000000f":         15,
+			"DeadBeef":         0xdeadbeef,
+			"ffffffffffffffff": math.MaxUint64,
+		} {
+			val, ok := parseHex([]byte(text))
+			Expect(ok).To(BeTrue(), "text %q", text)
+			Expect(val).To(Equal(expected), "text %q", text)
+		}
+		for _, text := range []string{"", "x", "0x1", "12g", "10000000000000000"} {
+			_, ok := parseHex([]byte(text))
+			Expect(ok).To(BeFalse(), "text %q", text)
+		}
+	})
+
+	It("appends comma-separated numbers", func() {
+		nums, ok := appendUint64CSV(nil, []byte("42"))
+		Expect(ok).To(BeTrue())
+		Expect(nums).To(HaveExactElements(uint64(42)))
+		nums, ok = appendUint64CSV([]uint64{1}, []byte("2,3"))
+		Expect(ok).To(BeTrue())
+		Expect(nums).To(HaveExactElements(uint64(1), uint64(2), uint64(3)))
+		buff := make([]uint64, 0, 4)
+		nums, ok = appendUint64CSV(buff, []byte("4,5"))
+		Expect(ok).To(BeTrue())
+		Expect(nums).To(HaveExactElements(uint64(4), uint64(5)))
+		Expect(cap(nums)).To(Equal(cap(buff)))
+		for _, text := range []string{"", ",", "1,", ",1", "1,,2", "1,x"} {
+			_, ok := appendUint64CSV(nil, []byte(text))
+			Expect(ok).To(BeFalse(), "text %q", text)
+		}
+	})
+
+})
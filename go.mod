@@ -2,12 +2,14 @@ module github.com/thediveo/irks
 
 go 1.23.4
 
-require github.com/onsi/ginkgo/v2 v2.22.2
+require (
+	github.com/onsi/ginkgo/v2 v2.22.2
+	github.com/thediveo/cpus v0.7.1
+	github.com/thediveo/faf v0.2.0
+)
 
 require (
 	github.com/google/go-cmp v0.6.0 // indirect
-	github.com/thediveo/cpus v0.7.1 // indirect
-	github.com/thediveo/faf v0.2.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,88 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/thediveo/faf"
+)
+
+const spuriousNode = "/spurious"
+
+// Health summarizes the health of a particular IRQ, as a sysadmin would
+// eyeball it from “/sys/kernel/irq/#/” and “/proc/irq/#/”.
+type Health struct {
+	HasActions    bool    // IRQ has at least one action assigned.
+	Orphaned      bool    // IRQ has neither effective nor configured CPU affinities.
+	Count         uint64  // number of interrupts counted in the current spurious detection window.
+	Unhandled     uint64  // number of unhandled interrupts in the current spurious detection window.
+	SpuriousRatio float64 // ratio of unhandled to counted interrupts.
+}
+
+// IRQHealth returns the Health of the specified IRQ, reporting false if the IRQ
+// doesn't exist.
+func IRQHealth(irqnum uint) (Health, bool) {
+	return irqHealth("", irqnum)
+}
+
+// irqHealth returns the Health of the specified IRQ, with the pseudo files
+// located beneath the specified root. The actions and effective affinities
+// come from the IRQ's details, so irqHealth gets the same fallbacks, such as
+// to the configured affinities or to “/proc/irq/#/” only.
+func irqHealth(root string, irqnum uint) (Health, bool) {
+	details, ok := detailsFor(root, irqnum)
+	if !ok {
+		return Health{}, false
+	}
+	health := Health{
+		HasActions: details.Actions != "",
+		Orphaned:   len(details.Affinities) == 0,
+	}
+	name := strconv.FormatUint(uint64(irqnum), 10)
+	if contents, ok := faf.ReadFile(root+procirqPath+name+spuriousNode, nil); ok {
+		health.Count, health.Unhandled, _ = parseSpurious(contents)
+		if health.Count > 0 {
+			health.SpuriousRatio = float64(health.Unhandled) / float64(health.Count)
+		}
+	}
+	return health, true
+}
+
+// parseSpurious returns the interrupt count and unhandled count from the
+// passed contents of a “/proc/irq/#/spurious” pseudo file, such as:
+//
+//	count 3
+//	unhandled 0
+//	last_unhandled 0 ms
+//
+// parseSpurious reports false if either count is missing or malformed.
+func parseSpurious(b []byte) (count, unhandled uint64, ok bool) {
+	var haveCount, haveUnhandled bool
+	for len(b) > 0 {
+		var line []byte
+		line, b, _ = bytes.Cut(b, []byte{'\n'})
+		key, rest := nextField(line)
+		value, _ := nextField(rest)
+		switch string(key) {
+		case "count":
+			count, haveCount = faf.ParseUint(value)
+		case "unhandled":
+			unhandled, haveUnhandled = faf.ParseUint(value)
+		}
+	}
+	return count, unhandled, haveCount && haveUnhandled
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IRQ health", func() {
+
+	It("parses spurious information", func() {
+		count, unhandled, ok := parseSpurious([]byte("count 100\nunhandled 5\nlast_unhandled 12 ms\n"))
+		Expect(ok).To(BeTrue())
+		Expect(count).To(Equal(uint64(100)))
+		Expect(unhandled).To(Equal(uint64(5)))
+
+		for _, text := range []string{"", "count 1\n", "unhandled 1\n", "count x\nunhandled 1\n"} {
+			_, _, ok := parseSpurious([]byte(text))
+			Expect(ok).To(BeFalse(), "text %q", text)
+		}
+	})
+
+	It("reports non-existing IRQs", func() {
+		_, ok := irqHealth("./testdata/mixed", 1)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports healthy IRQs", func() {
+		health, ok := irqHealth("./testdata/mixed", 42)
+		Expect(ok).To(BeTrue())
+		Expect(health).To(Equal(Health{
+			HasActions:    true,
+			Count:         100,
+			Unhandled:     5,
+			SpuriousRatio: 0.05,
+		}))
+	})
+
+	It("reports IRQs without actions and affinities", func() {
+		_, ok := irqHealth("./testdata/mixed", 444)
+		Expect(ok).To(BeFalse())
+
+		health, ok := irqHealth("./testdata/mixed", 46)
+		Expect(ok).To(BeTrue())
+		Expect(health).To(Equal(Health{}))
+
+		health, ok = irqHealth("./testdata/mixed", 667)
+		Expect(ok).To(BeTrue())
+		Expect(health).To(Equal(Health{HasActions: true, Orphaned: true}))
+	})
+
+	It("falls back to the configured affinities", func() {
+		health, ok := irqHealth("./testdata/mixed", 48)
+		Expect(ok).To(BeTrue())
+		Expect(health).To(Equal(Health{HasActions: true}))
+	})
+
+	It("falls back to /proc/irq when /sys/kernel/irq is unavailable", func() {
+		health, ok := irqHealth("./testdata/procirq-only", 5)
+		Expect(ok).To(BeTrue())
+		Expect(health).To(Equal(Health{}))
+	})
+
+	It("reads real IRQ health", func() {
+		for irq := range AllCounters() {
+			_, ok := IRQHealth(irq.Num)
+			Expect(ok).To(BeTrue())
+		}
+	})
+
+})
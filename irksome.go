@@ -16,10 +16,14 @@ package irks
 
 import (
 	"bufio"
+	"cmp"
+	"compress/gzip"
 	"io"
 	"iter"
 	"os"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/thediveo/faf"
 )
@@ -34,18 +38,129 @@ type IRQ struct {
 	CPUs     CPUList  // list of the number of the CPUs that are currently online.
 }
 
+// Clone returns a copy of this IRQ with its own copy of the per-CPU counters,
+// so that the copy can be safely retained beyond the yield call producing
+// this IRQ. The list of CPUs is shared, as it never gets modified.
+func (i IRQ) Clone() IRQ {
+	i.Counters = slices.Clone(i.Counters)
+	return i
+}
+
+// Total returns the sum of the per-CPU counters of this IRQ. As the counters
+// are transient, Total must be called only while the counters are valid.
+func (i IRQ) Total() uint64 {
+	var total uint64
+	for _, count := range i.Counters {
+		total += count
+	}
+	return total
+}
+
+// ActiveCPUs returns the number of CPUs this IRQ has fired on at least once,
+// that is, the number of non-zero per-CPU counters. As the counters are
+// transient, ActiveCPUs must be called only while the counters are valid.
+func (i IRQ) ActiveCPUs() int {
+	active := 0
+	for _, count := range i.Counters {
+		if count != 0 {
+			active++
+		}
+	}
+	return active
+}
+
+// HottestCPU returns the number of the CPU with the highest counter of this
+// IRQ, together with the counter. If multiple CPUs share the highest counter,
+// HottestCPU returns the lowest-numbered one. If there are no counters at all,
+// HottestCPU returns zero for both. As the counters are transient, HottestCPU
+// must be called only while the counters are valid.
+func (i IRQ) HottestCPU() (cpu uint, count uint64) {
+	hottest := -1
+	for idx := range min(len(i.CPUs), len(i.Counters)) {
+		if hottest < 0 || i.Counters[idx] > count {
+			hottest = idx
+			count = i.Counters[idx]
+		}
+	}
+	if hottest < 0 {
+		return 0, 0
+	}
+	return i.CPUs[hottest], count
+}
+
+// String returns a textual representation of this IRQ with its per-CPU
+// counters, such as “IRQ 42: CPU1=2 CPU42=3 CPU666=4”, mapping the counters to
+// the numbers of the CPUs online.
+func (i IRQ) String() string {
+	var b strings.Builder
+	b.WriteString("IRQ ")
+	b.WriteString(strconv.FormatUint(uint64(i.Num), 10))
+	b.WriteString(":")
+	for idx := range min(len(i.CPUs), len(i.Counters)) {
+		b.WriteString(" CPU")
+		b.WriteString(strconv.FormatUint(uint64(i.CPUs[idx]), 10))
+		b.WriteString("=")
+		b.WriteString(strconv.FormatUint(i.Counters[idx], 10))
+	}
+	return b.String()
+}
+
 // CPUList lists the numbers of the CPUs currently being online. It is used to
 // map indices of [IRQ] Counters elements to CPU numbers.
 type CPUList []uint
 
+// Index returns the index into the [IRQ] Counters for the specified CPU
+// number, reporting false if the CPU isn't in this list (such as when it is
+// offline). As the CPUs in “/proc/interrupts” are listed in ascending order,
+// Index uses a binary search.
+func (c CPUList) Index(cpu uint) (int, bool) {
+	return slices.BinarySearch(c, cpu)
+}
+
+// Contains reports whether the specified CPU number is in this list.
+func (c CPUList) Contains(cpu uint) bool {
+	_, ok := c.Index(cpu)
+	return ok
+}
+
+// IndexMap returns a map from the CPU numbers in this list to their indices
+// into the [IRQ] Counters, for callers doing many per-CPU lookups without
+// repeated binary searches. As CPUs might get hot(un)plugged between reading
+// “/proc/interrupts”, the map is only valid for the Counters belonging to this
+// particular CPUList, such as in the same [Snapshot], and must not be reused
+// for others.
+func (c CPUList) IndexMap() map[uint]int {
+	indices := make(map[uint]int, len(c))
+	for idx, cpu := range c {
+		indices[cpu] = idx
+	}
+	return indices
+}
+
+const procInterruptsPath = "/proc/interrupts"
+
 // AllCounters returns a single-use iterator that loops over “/proc/interrupts”
 // producing all (non-architecture-specific) IRQs.
 //
 // The produced IRQ information contains the per-CPU counters for a particular
 // IRQ, but only for CPUs that are currently online.
 func AllCounters() iter.Seq[IRQ] {
+	return AllCountersFrom("")
+}
+
+// AllCountersFrom returns a single-use iterator that loops over
+// “/proc/interrupts” located beneath the specified root, producing all
+// (non-architecture-specific) IRQs. For instance, when root is “/host”, then
+// the counters are read from “/host/proc/interrupts”. An empty root refers to
+// the root of the file system, so this then is the same as [AllCounters]. In
+// order to parse a single captured file located at an arbitrary path instead,
+// please use [ParseCountersFile].
+//
+// The produced IRQ information contains the per-CPU counters for a particular
+// IRQ, but only for CPUs that are currently online.
+func AllCountersFrom(root string) iter.Seq[IRQ] {
 	return func(yield func(IRQ) bool) {
-		f, err := os.Open("/proc/interrupts")
+		f, err := openProcInterrupts(root)
 		if err != nil {
 			return
 		}
@@ -56,22 +171,208 @@ func AllCounters() iter.Seq[IRQ] {
 
 // CountersFor returns a single-use iterator that loops over “/proc/interrupts”
 // producing only the requested IRQs, skipping non-existing IRQs. The list of
-// requested IRQs must be sorted in ascending order, but not in condescending
-// order.
+// requested IRQs should preferably be sorted in ascending order, but not in
+// condescending order. Otherwise, CountersFor sorts a copy of the list, leaving
+// the passed list untouched. CountersFor stops reading “/proc/interrupts” as
+// soon as it has passed the highest requested IRQ.
+//
+// The produced IRQ information contains the per-CPU counters for a particular
+// IRQ, but only for CPUs that are currently online.
+func CountersFor(irqnums []uint) iter.Seq[IRQ] {
+	return CountersForFrom("", irqnums)
+}
+
+// CountersForFrom returns a single-use iterator that loops over
+// “/proc/interrupts” located beneath the specified root, producing only the
+// requested IRQs, skipping non-existing IRQs. If the list of requested IRQs
+// isn't sorted in ascending order, then a sorted copy is used instead. An
+// empty root refers to the root of the file system, so this then is the same
+// as [CountersFor].
 //
 // The produced IRQ information contains the per-CPU counters for a particular
 // IRQ, but only for CPUs that are currently online.
-func CountersFor(sortedirqnums []uint) iter.Seq[IRQ] {
+func CountersForFrom(root string, irqnums []uint) iter.Seq[IRQ] {
+	irqnums = sortedIRQNums(irqnums)
 	return func(yield func(IRQ) bool) {
-		f, err := os.Open("/proc/interrupts")
+		f, err := openProcInterrupts(root)
 		if err != nil {
 			return
 		}
 		defer f.Close()
-		iterateAllCounters(f, sortedirqnums, yield)
+		iterateAllCounters(f, irqnums, yield)
 	}
 }
 
+// ActiveCounters returns a single-use iterator that loops over
+// “/proc/interrupts” producing only the active (non-architecture-specific)
+// IRQs, that is, IRQs with at least one non-zero per-CPU counter. Please note
+// that “active” means that an IRQ has fired at least once since boot on any of
+// the CPUs currently online, but not necessarily that it is currently firing.
+//
+// The produced IRQ information contains the per-CPU counters for a particular
+// IRQ, but only for CPUs that are currently online.
+func ActiveCounters() iter.Seq[IRQ] {
+	return activeCounters(AllCounters())
+}
+
+// activeCounters returns an iterator producing only those IRQs from the
+// specified iterator that have a non-zero total count.
+func activeCounters(it iter.Seq[IRQ]) iter.Seq[IRQ] {
+	return func(yield func(IRQ) bool) {
+		for irq := range it {
+			if irq.Total() == 0 {
+				continue
+			}
+			if !yield(irq) {
+				return
+			}
+		}
+	}
+}
+
+// TopCounters returns the n IRQs with the highest total counts across all
+// CPUs currently online, in descending order of their totals; IRQs with the
+// same total are ordered by ascending IRQ number. In contrast to the streaming
+// iterators, TopCounters necessarily buffers a full pass over
+// “/proc/interrupts”, as ranking requires seeing all IRQs first. The returned
+// IRQs own their Counters and thus can be safely retained. If n isn't
+// positive, TopCounters returns nil.
+func TopCounters(n int) []IRQ {
+	return topCounters(AllCounters(), n)
+}
+
+// topCounters returns the n IRQs with the highest total counts from the
+// specified iterator.
+func topCounters(it iter.Seq[IRQ], n int) []IRQ {
+	if n <= 0 {
+		return nil
+	}
+	type rankedIRQ struct {
+		irq   IRQ
+		total uint64
+	}
+	var ranked []rankedIRQ
+	for irq := range it {
+		ranked = append(ranked, rankedIRQ{irq: irq.Clone(), total: irq.Total()})
+	}
+	slices.SortFunc(ranked, func(a, b rankedIRQ) int {
+		if c := cmp.Compare(b.total, a.total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.irq.Num, b.irq.Num)
+	})
+	top := make([]IRQ, 0, min(n, len(ranked)))
+	for _, r := range ranked[:min(n, len(ranked))] {
+		top = append(top, r.irq)
+	}
+	return top
+}
+
+// CountersForCPU returns a single-use iterator that loops over
+// “/proc/interrupts” producing the IRQ numbers together with their counters for
+// only the specified CPU. If the CPU is offline or doesn't exist, the iterator
+// produces nothing.
+func CountersForCPU(cpu uint) iter.Seq2[uint, uint64] {
+	return countersForCPU(AllCounters(), cpu)
+}
+
+// countersForCPU returns an iterator producing the IRQ numbers and their
+// counters for only the specified CPU, based on the IRQs produced by the
+// specified iterator.
+func countersForCPU(it iter.Seq[IRQ], cpu uint) iter.Seq2[uint, uint64] {
+	return func(yield func(uint, uint64) bool) {
+		// All IRQs share the same list of online CPUs, so we need to resolve
+		// the CPU to its counter index only once.
+		idx := -1
+		for irq := range it {
+			if idx < 0 {
+				var ok bool
+				if idx, ok = irq.CPUs.Index(cpu); !ok {
+					return
+				}
+			}
+			if !yield(irq.Num, irq.Counters[idx]) {
+				return
+			}
+		}
+	}
+}
+
+// ParseCounters returns a single-use iterator that loops over the IRQs with
+// their per-CPU counters, as read from the specified reader in
+// “/proc/interrupts” format. This allows parsing IRQ counters captured
+// elsewhere, such as on a remote host.
+func ParseCounters(r io.Reader) iter.Seq[IRQ] {
+	return allCounters(r, nil)
+}
+
+// ParseCountersFor returns a single-use iterator that loops over only the
+// requested IRQs with their per-CPU counters, as read from the specified reader
+// in “/proc/interrupts” format. If the list of requested IRQs isn't sorted in
+// ascending order, then a sorted copy is used instead.
+func ParseCountersFor(r io.Reader, irqnums []uint) iter.Seq[IRQ] {
+	return allCounters(r, sortedIRQNums(irqnums))
+}
+
+// ParseCountersFile returns a single-use iterator that loops over the IRQs with
+// their per-CPU counters, as read from the specified file in
+// “/proc/interrupts” format. If the file name ends in “.gz”, then the file
+// contents are transparently decompressed, such as when working with
+// compressed diagnostic snapshots. If the file cannot be opened or
+// decompressed, the iterator produces nothing.
+func ParseCountersFile(path string) iter.Seq[IRQ] {
+	return func(yield func(IRQ) bool) {
+		r, err := openCountersFile(path)
+		if err != nil {
+			return
+		}
+		defer r.Close()
+		iterateAllCounters(r, nil, yield)
+	}
+}
+
+// openCountersFile opens the specified file in “/proc/interrupts” format,
+// transparently decompressing it if its name ends in “.gz”.
+func openCountersFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipFile{Reader: gzr, f: f}, nil
+}
+
+// gzipFile decompresses an opened file, closing both the decompressor and the
+// file when done.
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Reader.Close()
+	if ferr := g.f.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// sortedIRQNums returns the passed list of IRQ numbers if it is already sorted
+// in ascending order, otherwise a sorted copy of it.
+func sortedIRQNums(irqnums []uint) []uint {
+	if slices.IsSorted(irqnums) {
+		return irqnums
+	}
+	return slices.Sorted(slices.Values(irqnums))
+}
+
 // allCounters returns an iterator looping over the IRQs with their per-CPU
 // counters based on the information in “/proc/interrupts” format and produced
 // by the specified reader.
@@ -89,7 +390,7 @@ func iterateAllCounters(r io.Reader, irqnums []uint, yield func(IRQ) bool) {
 	// Please note that sc.Bytes() returns a slice referencing the scanners
 	// internal memory that becomes invalid with advancing to the next
 	// line/token.
-	sc := bufio.NewScanner(r)
+	sc := newProcInterruptsScanner(r)
 	if !sc.Scan() {
 		return
 	}
@@ -104,25 +405,48 @@ func iterateAllCounters(r io.Reader, irqnums []uint, yield func(IRQ) bool) {
 		CPUs:     cpus,
 		Counters: make([]uint64, len(cpus)),
 	}
+	// When filtering, we can stop early after having passed the highest
+	// requested IRQ number, as the kernel lists the IRQs in ascending order.
+	// Just in case, we fall back to scanning all lines when we ever come
+	// across IRQ numbers not in ascending order.
+	var maxIRQ uint64
+	if irqnums != nil {
+		if len(irqnums) == 0 {
+			return
+		}
+		maxIRQ = uint64(irqnums[len(irqnums)-1])
+	}
+	ascending := true
+	lastIRQ := int64(-1)
+nextLine:
 	for sc.Scan() {
 		// Fetch the IRQ number from the beginning of the current text line,
-		// ending the iteration when encountering an "unnumbered"
-		// (architecture specific) IRQ.
+		// skipping "unnumbered" (architecture specific) IRQs, as well as
+		// malformed or truncated lines, so these don't hide any following
+		// IRQs.
 		bstr := faf.NewBytestring(sc.Bytes())
 		if bstr.SkipSpace() {
-			return
+			continue
 		}
 		irqno, ok := bstr.Uint64()
 		if !ok {
-			return
+			continue
 		}
 		if !bstr.SkipText(":") {
-			return
+			continue
+		}
+
+		if int64(irqno) <= lastIRQ {
+			ascending = false
 		}
+		lastIRQ = int64(irqno)
 
 		// If IRQ filtering is in place, take heed.
 		if irqnums != nil {
 			if _, ok := slices.BinarySearch(irqnums, uint(irqno)); !ok {
+				if ascending && irqno > maxIRQ {
+					return
+				}
 				continue
 			}
 		}
@@ -131,33 +455,100 @@ func iterateAllCounters(r io.Reader, irqnums []uint, yield func(IRQ) bool) {
 		// Now consume the per-CPU counters
 		for idx := 0; idx < numCPUs; idx++ {
 			if bstr.SkipSpace() {
-				return
+				continue nextLine
 			}
 			count, ok := bstr.Uint64()
 			if !ok {
-				return
+				continue nextLine
 			}
 			irq.Counters[idx] = count
 		}
+		// Skip lines having more counter columns than the header has CPUs, as
+		// we cannot tell which counter belongs to which CPU. Similar to lines
+		// with fewer counter columns, this might happen transiently during CPU
+		// hotplug.
+		if excessCounter(bstr) {
+			continue
+		}
 
 		// Push the counters for this IRQ to the consumer of this iterator.
 		if !yield(irq) {
 			return
 		}
+		if irqnums != nil && ascending && irqno >= maxIRQ {
+			return
+		}
+	}
+}
+
+// OnlineCPUs returns the list of CPUs that are currently online, as read from
+// the header line of “/proc/interrupts”, without parsing any of the IRQ lines.
+// OnlineCPUs reports false if the CPUs cannot be determined.
+func OnlineCPUs() (CPUList, bool) {
+	return onlineCPUs("")
+}
+
+// onlineCPUs returns the list of CPUs that are currently online, with
+// “/proc/interrupts” located beneath the specified root.
+func onlineCPUs(root string) (CPUList, bool) {
+	f, err := openProcInterrupts(root)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	return onlineCPUsOf(f)
+}
+
+// onlineCPUsOf returns the list of CPUs that are currently online, as read
+// from the header line of the information in “/proc/interrupts” format
+// produced by the specified reader.
+func onlineCPUsOf(r io.Reader) (CPUList, bool) {
+	sc := newProcInterruptsScanner(r)
+	if !sc.Scan() {
+		return nil, false
 	}
+	cpus := cpuListFromProcInterrupts(sc.Bytes())
+	return cpus, len(cpus) > 0
+}
+
+// maxProcInterruptsLineLength is the maximum length of a single text line in
+// “/proc/interrupts” we're willing to accept. With each CPU taking up 11
+// characters per line, this supports well beyond a million CPUs.
+const maxProcInterruptsLineLength = 16 * 1024 * 1024
+
+// newProcInterruptsScanner returns a new line scanner for the specified reader
+// that grows its buffer as necessary beyond [bufio.MaxScanTokenSize] in order
+// to cope with the very long lines on systems with many CPUs. Additionally, the
+// scanner replaces any tabs with spaces, please see [scanUntabbedLines].
+func newProcInterruptsScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxProcInterruptsLineLength)
+	sc.Split(scanUntabbedLines)
+	return sc
+}
+
+// scanUntabbedLines is a [bufio.SplitFunc] that works like [bufio.ScanLines],
+// but additionally replaces any tabs in the returned lines with spaces. This
+// way, the parsers can stick to skipping only spaces in their hot paths, yet
+// correctly handle captured or edited “/proc/interrupts” data using tabs.
+func scanUntabbedLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = bufio.ScanLines(data, atEOF)
+	untab(token)
+	return advance, token, err
 }
 
 // cpuListFromProcInterrupts returns the list of CPUs that are currently online,
 // according to the passed text line that must be in the format of the header
-// line from “/proc/interrupts”.
+// line from “/proc/interrupts”. Only the leading “CPU<n>” fields are taken into
+// account, so that any trailing non-CPU column doesn't throw away the CPUs
+// already found.
 func cpuListFromProcInterrupts(b []byte) CPUList {
 	bstr := faf.NewBytestring(b)
-	numCPUs := bstr.NumFields()
-	if numCPUs == 0 {
+	numFields := bstr.NumFields()
+	if numFields == 0 {
 		return nil
 	}
-	cpuNums := make(CPUList, numCPUs)
-	idx := 0
+	cpuNums := make(CPUList, 0, numFields)
 	for {
 		if bstr.SkipSpace() {
 			break
@@ -169,10 +560,9 @@ func cpuListFromProcInterrupts(b []byte) CPUList {
 		if !ok {
 			break
 		}
-		cpuNums[idx] = uint(cpuNum)
-		idx++
+		cpuNums = append(cpuNums, uint(cpuNum))
 	}
-	if idx != numCPUs {
+	if len(cpuNums) == 0 {
 		return nil
 	}
 	return cpuNums
@@ -15,31 +15,140 @@
 package irks
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"io/fs"
 	"iter"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/thediveo/cpus"
 	"github.com/thediveo/faf"
 )
 
 // IRQDetails provides the list of actions and the currently set CPU affinities
-// for a specific IRQ, as indicated by Num.
+// for a specific IRQ, as indicated by Num. Additionally, it provides structural
+// information about the IRQ chip, hardware IRQ, flow handler, trigger type, and
+// wakeup state, where available; otherwise, these fields are left zero.
+//
+// In restricted environments where only one of “/sys/kernel/irq/#/” and
+// “/proc/irq/#/” is accessible, the details of an IRQ lack either the actions
+// or the effective affinities. On kernels that don't show the effective
+// affinities, Affinities falls back to the configured affinities.
+//
+// The names of the IRQ domains mapping the hardware IRQs to the Linux IRQ
+// numbers are only available from debugfs, please see [IRQDomains].
 type IRQDetails struct {
-	Num        uint      // IRQ number
-	Actions    string    // list of IRQ actions
-	Affinities cpus.List // effective CPU(s) affinities
+	Num                  uint      // IRQ number
+	Actions              string    // list of IRQ actions
+	Affinities           cpus.List // effective CPU(s) affinities, falling back to configured ones
+	ConfiguredAffinities cpus.List // configured CPU(s) affinities, if available
+	AffinityHint         cpus.List // driver-suggested CPU(s) affinities, if any
+	ChipName             string    // name of the IRQ chip, if available
+	HwIRQ                uint64    // hardware IRQ number, if available
+	FlowName             string    // name of the flow handler, such as "edge", if available
+	TriggerType          string    // either "edge" or "level", if available
+	Wakeup               bool      // true if the wakeup state is "enabled"
+	PerCPU               bool      // true if (heuristically) a per-CPU interrupt
+	Node                 int       // NUMA node, or -1 if not associated/available
+}
+
+// ActionList returns the individual actions of this IRQ as a slice. In case
+// there are no actions, ActionList returns an empty slice.
+func (d IRQDetails) ActionList() []string {
+	if d.Actions == "" {
+		return []string{}
+	}
+	return strings.Split(d.Actions, ",")
+}
+
+// EachAction yields the individual actions of this IRQ in order, without
+// allocating a slice of action names as [IRQDetails.ActionList] does. If there
+// are no actions, EachAction yields nothing. A trailing comma doesn't yield a
+// final empty action.
+func (d IRQDetails) EachAction(yield func(string) bool) {
+	actions := d.Actions
+	for actions != "" {
+		action, rest, _ := strings.Cut(actions, ",")
+		if !yield(action) {
+			return
+		}
+		actions = rest
+	}
+}
+
+// IsMSI reports whether this IRQ is an MSI or MSI-X interrupt. Please note that
+// this is a heuristic based on the kernel-reported IRQ chip name containing
+// “MSI”, such as in “IR-PCI-MSI-0000:00:14.0” or “ITS-MSI”.
+func (d IRQDetails) IsMSI() bool {
+	return strings.Contains(d.ChipName, "MSI")
+}
+
+// IsShared reports whether this IRQ is shared by multiple actions, such as
+// when multiple devices share the same IRQ line. Empty actions, such as from a
+// dangling comma, are not counted.
+func (d IRQDetails) IsShared() bool {
+	count := 0
+	return hasAction(d.Actions, func(action string) bool {
+		if action != "" {
+			count++
+		}
+		return count > 1
+	})
+}
+
+// IsWakeup reports whether this IRQ is able to wake up the system, as its
+// wakeup state is “enabled”.
+func (d IRQDetails) IsWakeup() bool {
+	return d.Wakeup
 }
 
 // AllIRQDetails returns an iterator looping over the details of all
 // (non-architecture-specific) IRQs in the system, giving their details as to
 // actions and CPU affinities.
 //
-// AllIRQDetails uses a streamlined implementation that runs at approx 1.8× the
-// execution speed compared to a “traditional” Go implementation approach using
-// [os.File.ReadDir], [strconv.ParseUint] and [os.ReadFile]. For the same system
-// with 47 hardware IRQs, we only need at around 10% of memory on the heap, and
-// only 1/3 of allocations, compared to using stock stdlib functions.
+// For each IRQ, AllIRQDetails reads around a dozen pseudo files from
+// “/sys/kernel/irq/#/” and “/proc/irq/#/”, but never from debugfs. To keep
+// this affordable, AllIRQDetails reuses a single read buffer and avoids
+// [os.File.ReadDir] and [os.ReadFile] in favor of less allocation-heavy file
+// system access; please see the benchmarks for figures.
 func AllIRQDetails() iter.Seq[IRQDetails] {
-	return allIRQDetails("")
+	return AllIRQDetailsContext(context.Background())
+}
+
+// AllIRQDetailsContext returns an iterator looping over the details of all
+// (non-architecture-specific) IRQs in the system, similar to [AllIRQDetails].
+// Additionally, the iteration ends early when the specified context gets
+// cancelled.
+func AllIRQDetailsContext(ctx context.Context) iter.Seq[IRQDetails] {
+	return allIRQDetails(ctx, "")
+}
+
+// AllIRQDetailsSorted returns an iterator looping over the details of all
+// (non-architecture-specific) IRQs in the system, in ascending order of their
+// IRQ numbers. In contrast to [AllIRQDetails] that streams the details in
+// directory order, AllIRQDetailsSorted first needs to gather the details of all
+// IRQs before it can yield the first one. This adds latency as well as memory
+// proportional to the number of IRQs.
+func AllIRQDetailsSorted() iter.Seq[IRQDetails] {
+	return sortedIRQDetails(AllIRQDetails())
+}
+
+// sortedIRQDetails returns an iterator looping over the IRQ details produced
+// by the specified iterator, but sorted in ascending order of IRQ numbers.
+func sortedIRQDetails(it iter.Seq[IRQDetails]) iter.Seq[IRQDetails] {
+	return func(yield func(IRQDetails) bool) {
+		details := slices.SortedFunc(it, func(a, b IRQDetails) int {
+			return cmp.Compare(a.Num, b.Num)
+		})
+		for _, detail := range details {
+			if !yield(detail) {
+				return
+			}
+		}
+	}
 }
 
 const (
@@ -47,10 +156,56 @@ const (
 	procirqPath      = "/proc/irq/"
 
 	actionsNode           = "/actions"
+	chipNameNode          = "/chip_name"
+	hwirqNode             = "/hwirq"
+	nameNode              = "/name"
+	typeNode              = "/type"
+	wakeupNode            = "/wakeup"
 	effectiveAffinityNode = "/effective_affinity_list"
+	smpAffinityNode       = "/smp_affinity_list"
+	smpAffinityMaskNode   = "/smp_affinity"
+	affinityHintNode      = "/affinity_hint"
+	nodeNode              = "/node"
 )
 
-func allIRQDetails(root string) iter.Seq[IRQDetails] {
+// AllIRQDetailsFS returns an iterator looping over the details of all
+// (non-architecture-specific) IRQs, similar to [AllIRQDetails], but reading the
+// pseudo files through the specified [fs.FS] instead. The file system must
+// contain the “sys/kernel/irq/” and “proc/irq/” hierarchies at its top level.
+// This allows, for instance, working on captured pseudo files or using a
+// [testing/fstest.MapFS].
+func AllIRQDetailsFS(fsys fs.FS) iter.Seq[IRQDetails] {
+	return irqDetailsFrom(context.Background(), fsPseudoFS(fsys), "", -1)
+}
+
+// AllIRQDetailsLimit returns an iterator looping over the details of only the
+// first n IRQs found in directory order, similar to [AllIRQDetails]. This
+// allows taking a quick sample on systems with thousands of IRQs. Please note
+// that the limit applies to the IRQ directories scanned, so IRQs whose details
+// cannot be read count against the limit, and thus fewer than n IRQ details
+// might be produced. For n <= 0, AllIRQDetailsLimit produces nothing.
+func AllIRQDetailsLimit(n int) iter.Seq[IRQDetails] {
+	return limitedIRQDetails("", n)
+}
+
+func allIRQDetails(ctx context.Context, root string) iter.Seq[IRQDetails] {
+	return irqDetailsFrom(ctx, fafPseudoFS, root, -1)
+}
+
+// limitedIRQDetails returns an iterator looping over the details of at most n
+// IRQs, with the pseudo files located beneath the specified root.
+func limitedIRQDetails(root string, n int) iter.Seq[IRQDetails] {
+	if n <= 0 {
+		return func(func(IRQDetails) bool) {}
+	}
+	return irqDetailsFrom(context.Background(), fafPseudoFS, root, n)
+}
+
+// irqDetailsFrom returns an iterator looping over the details of all IRQs,
+// reading the pseudo files located beneath the specified root via the
+// specified pseudo file system access. If limit isn't negative, then the
+// iterator stops after scanning limit IRQ directories.
+func irqDetailsFrom(ctx context.Context, pfs pseudoFS, root string, limit int) iter.Seq[IRQDetails] {
 	return func(yield func(IRQDetails) bool) {
 		// Using bytes.Buffer instead of assembling path strings piecewise
 		// doesn't buy us anything above the noise floor, even with
@@ -58,37 +213,292 @@ func allIRQDetails(root string) iter.Seq[IRQDetails] {
 		// the root. But reusing the buffer to read the pseudo files boosts us...
 		var contents []byte
 		var details IRQDetails
-		for irqEntry := range faf.ReadDir(root + syskernelirqPath) {
-			if !irqEntry.IsDir() {
-				continue
+		// Normally, we discover the IRQs from “/sys/kernel/irq/”, but in case
+		// that isn't available, we fall back to discovering them from
+		// “/proc/irq/” instead.
+		for _, irqsPath := range []string{syskernelirqPath, procirqPath} {
+			// When discovering the IRQs from “/sys/kernel/irq/”, we need to
+			// skip allocated, but unrequested IRQs: these lack a
+			// “/proc/irq/#/” directory and don't show up in
+			// “/proc/interrupts”. Only if “/proc/irq/” isn't available as a
+			// whole, we take all IRQs as they come.
+			var requested map[string]struct{}
+			if irqsPath == syskernelirqPath {
+				requested = procIRQNames(pfs, root)
 			}
-			irqnum, ok := faf.ParseUint(irqEntry.Name)
-			if !ok {
-				continue
+			found := false
+			for irqname := range pfs.subdirs(root + irqsPath) {
+				if ctx.Err() != nil {
+					return
+				}
+				irqnum, ok := faf.ParseUint(irqname)
+				if !ok {
+					continue
+				}
+				found = true
+				if limit == 0 {
+					return
+				}
+				limit--
+				if requested != nil {
+					if _, ok := requested[string(irqname)]; !ok {
+						continue
+					}
+				}
+				details.Num = uint(irqnum)
+				contents, ok = readIRQDetails(pfs, root, string(irqname), &details, contents)
+				if !ok {
+					continue
+				}
+				if !yield(details) {
+					return
+				}
 			}
-			details.Num = uint(irqnum)
+			if found {
+				return
+			}
+		}
+	}
+}
+
+// DetailsFor returns the details of only the specified IRQ, reading them
+// directly from “/sys/kernel/irq/#/” and “/proc/irq/#/” without scanning all
+// IRQs. DetailsFor reports false if the IRQ doesn't exist or its details
+// cannot be read.
+func DetailsFor(irqnum uint) (IRQDetails, bool) {
+	return detailsFor("", irqnum)
+}
+
+// detailsFor returns the details of the specified IRQ, with the pseudo files
+// located beneath the specified root.
+func detailsFor(root string, irqnum uint) (IRQDetails, bool) {
+	irqname := strconv.FormatUint(uint64(irqnum), 10)
+	if !isRequestedIRQ(fafPseudoFS, root, irqname, hasProcIRQs(fafPseudoFS, root)) {
+		return IRQDetails{}, false
+	}
+	details := IRQDetails{Num: irqnum}
+	if _, ok := readIRQDetails(fafPseudoFS, root, irqname, &details, nil); !ok {
+		return IRQDetails{}, false
+	}
+	return details, true
+}
+
+// DetailsForNums returns an iterator looping over the details of only the
+// requested IRQs, in ascending order of their IRQ numbers, skipping
+// non-existing IRQs. In contrast to [AllIRQDetails], DetailsForNums doesn't
+// scan all IRQs but instead directly reads the details of the requested IRQs.
+// If the list of requested IRQs isn't sorted in ascending order, then a sorted
+// copy is used instead.
+func DetailsForNums(irqnums []uint) iter.Seq[IRQDetails] {
+	return detailsForNums("", sortedIRQNums(irqnums))
+}
 
-			contents, ok := faf.ReadFile(
-				root+syskernelirqPath+string(irqEntry.Name)+actionsNode, contents)
-			if !ok || len(contents) < 1 || contents[len(contents)-1] != '\n' {
+// detailsForNums returns an iterator looping over the details of the IRQs in
+// the specified sorted list, with the pseudo files located beneath the
+// specified root.
+func detailsForNums(root string, irqnums []uint) iter.Seq[IRQDetails] {
+	return func(yield func(IRQDetails) bool) {
+		var contents []byte
+		var details IRQDetails
+		procIRQs := hasProcIRQs(fafPseudoFS, root)
+		for idx, irqnum := range irqnums {
+			if idx > 0 && irqnums[idx-1] == irqnum {
 				continue
 			}
-			details.Actions = string(contents[:len(contents)-1]) // escapes
-
-			contents, ok = faf.ReadFile(
-				root+procirqPath+string(irqEntry.Name)+effectiveAffinityNode, contents)
-			if !ok || len(contents) < 1 || contents[len(contents)-1] != '\n' {
+			irqname := strconv.FormatUint(uint64(irqnum), 10)
+			if !isRequestedIRQ(fafPseudoFS, root, irqname, procIRQs) {
 				continue
 			}
-			afflist, err := cpus.NewList(contents[:len(contents)-1])
-			if err != nil || len(afflist) == 0 {
+			details.Num = irqnum
+			var ok bool
+			contents, ok = readIRQDetails(fafPseudoFS, root, irqname, &details, contents)
+			if !ok {
 				continue
 			}
-			details.Affinities = afflist
-
 			if !yield(details) {
 				return
 			}
 		}
 	}
 }
+
+// procIRQNames returns the set of IRQ names (numbers in textual form) that
+// have a directory in “/proc/irq/”, that is, the requested IRQs. If
+// “/proc/irq/” isn't available or doesn't contain any IRQ directories at all,
+// procIRQNames returns nil.
+func procIRQNames(pfs pseudoFS, root string) map[string]struct{} {
+	var names map[string]struct{}
+	for irqname := range pfs.subdirs(root + procirqPath) {
+		if _, ok := faf.ParseUint(irqname); !ok {
+			continue
+		}
+		if names == nil {
+			names = map[string]struct{}{}
+		}
+		names[string(irqname)] = struct{}{}
+	}
+	return names
+}
+
+// hasProcIRQs reports whether “/proc/irq/” is available, containing at least
+// a single IRQ directory.
+func hasProcIRQs(pfs pseudoFS, root string) bool {
+	for irqname := range pfs.subdirs(root + procirqPath) {
+		if _, ok := faf.ParseUint(irqname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isRequestedIRQ reports whether the IRQ with the specified name has been
+// requested, as indicated by the presence of its “/proc/irq/#/” directory. If
+// “/proc/irq/” isn't available as a whole, as indicated by procIRQs being
+// false, then any IRQ is taken as requested.
+func isRequestedIRQ(pfs pseudoFS, root string, irqname string, procIRQs bool) bool {
+	return !procIRQs || pfs.isDir(root+procirqPath+irqname)
+}
+
+// readIRQDetails reads the details of the IRQ with the specified name (that is,
+// its number in textual form) into the passed details, with the exception of
+// the IRQ number that must already have been set by the caller. It reports
+// false if neither the actions nor any affinities are available, or if the
+// actions are malformed. Effective affinities that are present, but empty or
+// malformed are reported as empty affinities. The passed contents buffer gets
+// reused for reading the pseudo files and is returned for further reuse.
+func readIRQDetails(pfs pseudoFS, root string, irqname string, details *IRQDetails, contents []byte) ([]byte, bool) {
+	irqPath := root + syskernelirqPath + irqname
+	// In restricted environments either the actions or the effective
+	// affinities might not be available, so we gracefully degrade and report
+	// what we can get, unless we get neither. An IRQ without any actions has
+	// an empty actions pseudo file, so we must not mistake this as an error.
+	hasActions := false
+	details.Actions = ""
+	contents, ok := pfs.readFile(irqPath+actionsNode, contents)
+	if ok {
+		if len(contents) > 0 {
+			line, ok := lineOf(contents)
+			if !ok {
+				return contents, false
+			}
+			details.Actions = string(line) // escapes
+		}
+		hasActions = true
+	}
+
+	procIRQPath := root + procirqPath + irqname
+	hasAffinities := false
+	details.Affinities = nil
+	if contents, ok = pfs.readFile(procIRQPath+effectiveAffinityNode, contents); ok {
+		// An effective affinity list that is present, yet empty or malformed,
+		// still is information, so we report it as empty affinities instead
+		// of falling back to the configured affinities.
+		if line, ok := lineOf(contents); ok {
+			if afflist, err := cpus.NewList(line); err == nil && len(afflist) > 0 {
+				details.Affinities = afflist
+			}
+		}
+		hasAffinities = true
+	}
+
+	// The configured affinities are optional, as some IRQs don't have
+	// writable affinities. Older kernels might only show the configured
+	// affinities in hex mask form.
+	details.ConfiguredAffinities = nil
+	if contents, ok = pfs.readFile(procIRQPath+smpAffinityNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			if afflist, err := cpus.NewList(line); err == nil && len(afflist) > 0 {
+				details.ConfiguredAffinities = afflist
+			}
+		}
+	} else if contents, ok = pfs.readFile(procIRQPath+smpAffinityMaskNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			if afflist, err := cpuMask(line); err == nil && len(afflist) > 0 {
+				details.ConfiguredAffinities = afflist
+			}
+		}
+	}
+
+	// Kernels without CONFIG_GENERIC_IRQ_EFFECTIVE_AFF_MASK lack the effective
+	// affinities, so we then fall back to the configured affinities instead.
+	if !hasAffinities && details.ConfiguredAffinities != nil {
+		details.Affinities = details.ConfiguredAffinities
+		hasAffinities = true
+	}
+	if !hasActions && !hasAffinities {
+		return contents, false
+	}
+
+	// The affinity hint is only set by some drivers and otherwise is all
+	// zeros, which we then report as no hint at all.
+	details.AffinityHint = nil
+	if contents, ok = pfs.readFile(procIRQPath+affinityHintNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			if afflist, err := cpuMask(line); err == nil && len(afflist) > 0 {
+				details.AffinityHint = afflist
+			}
+		}
+	}
+
+	details.Node = -1
+	if contents, ok = pfs.readFile(procIRQPath+nodeNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			if node, ok := parseInt(line); ok {
+				details.Node = int(node)
+			}
+		}
+	}
+
+	// The following pseudo files are optional, depending on the kernel
+	// configuration and IRQ, so we leave their fields zero if missing.
+	details.ChipName = ""
+	if contents, ok = pfs.readFile(irqPath+chipNameNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			details.ChipName = string(line)
+		}
+	}
+	details.HwIRQ = 0
+	if contents, ok = pfs.readFile(irqPath+hwirqNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			details.HwIRQ, _ = faf.ParseUint(line)
+		}
+	}
+	details.FlowName = ""
+	if contents, ok = pfs.readFile(irqPath+nameNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			details.FlowName = string(line)
+		}
+	}
+	details.PerCPU = isPerCPUFlow(details.FlowName)
+	details.TriggerType = ""
+	if contents, ok = pfs.readFile(irqPath+typeNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			details.TriggerType = string(line)
+		}
+	}
+	details.Wakeup = false
+	if contents, ok = pfs.readFile(irqPath+wakeupNode, contents); ok {
+		if line, ok := lineOf(contents); ok {
+			details.Wakeup = string(bytes.TrimSpace(line)) == "enabled"
+		}
+	}
+	return contents, true
+}
+
+// isPerCPUFlow reports whether the specified flow handler name indicates a
+// per-CPU interrupt, such as a local timer or an IPI. This is a heuristic, as
+// there's no dedicated pseudo file indicating per-CPU interrupts: the kernel
+// names the flow handlers of per-CPU interrupts “percpu” or “percpu_devid”,
+// yet drivers are free to name their flow handlers as they see fit.
+func isPerCPUFlow(flowName string) bool {
+	return strings.HasPrefix(flowName, "percpu")
+}
+
+// lineOf returns the passed pseudo file contents without its trailing newline,
+// reporting false if the contents are not a properly newline-terminated line.
+func lineOf(contents []byte) ([]byte, bool) {
+	if len(contents) < 1 || contents[len(contents)-1] != '\n' {
+		return nil, false
+	}
+	return contents[:len(contents)-1], true
+}
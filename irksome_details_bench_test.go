@@ -15,6 +15,7 @@
 package irks
 
 import (
+	"context"
 	"iter"
 	"os"
 	"strconv"
@@ -36,6 +37,11 @@ BenchmarkIRQDetailsOsReadDir-4              9818           1166176 ns/op
 BenchmarkIRQDetails                        17660            675672 ns/op           65787 B/op        413 allocs/op
 BenchmarkIRQDetails-4                      18022            662177 ns/op           65795 B/op        413 allocs/op
 
+Please note that these figures were taken when AllIRQDetails read only the
+actions and effective affinities, same as the reference implementation below
+still does. Since then, AllIRQDetails reads many more pseudo files per IRQ, so
+the figures are not comparable anymore.
+
 */
 
 // AllIRQDetailsOsReadDir does it the traditional Gopher way, using
@@ -99,7 +105,7 @@ func BenchmarkIRQDetailsOsReadDir(b *testing.B) {
 // faf.ParseUint, et cetera.
 func BenchmarkIRQDetails(b *testing.B) {
 	for n := 0; n < b.N; n++ {
-		for range allIRQDetails("") {
+		for range allIRQDetails(context.Background(), "") {
 		}
 	}
 }
@@ -0,0 +1,108 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"iter"
+	"strings"
+
+	"github.com/thediveo/cpus"
+)
+
+// DetailsForAction returns an iterator looping over the details of only those
+// IRQs that have at least one action containing the specified substring. For
+// instance, "eth0" matches the actions “eth0” as well as “eth0-rx-1”.
+func DetailsForAction(substr string) iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), func(details IRQDetails) bool {
+		return hasAction(details.Actions, func(action string) bool {
+			return strings.Contains(action, substr)
+		})
+	})
+}
+
+// DetailsForExactAction returns an iterator looping over the details of only
+// those IRQs that have an action exactly matching the specified action name.
+// For instance, "eth0" matches “eth0”, but not “eth0-rx-1”.
+func DetailsForExactAction(action string) iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), func(details IRQDetails) bool {
+		return hasAction(details.Actions, func(a string) bool {
+			return a == action
+		})
+	})
+}
+
+// AllMSIDetails returns an iterator looping over the details of only the MSI
+// and MSI-X IRQs, please see [IRQDetails.IsMSI] for details.
+func AllMSIDetails() iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), IRQDetails.IsMSI)
+}
+
+// AllSharedDetails returns an iterator looping over the details of only those
+// IRQs that are shared by multiple actions, please see [IRQDetails.IsShared]
+// for details.
+func AllSharedDetails() iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), IRQDetails.IsShared)
+}
+
+// AllWakeupDetails returns an iterator looping over the details of only those
+// IRQs that are able to wake up the system, please see [IRQDetails.IsWakeup]
+// for details.
+func AllWakeupDetails() iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), IRQDetails.IsWakeup)
+}
+
+// IRQsAffineTo returns an iterator looping over the details of only those IRQs
+// whose effective CPU affinities include the specified CPU. This helps, for
+// instance, with verifying that isolated CPUs are indeed free of IRQs.
+func IRQsAffineTo(cpu uint) iter.Seq[IRQDetails] {
+	return filterDetails(AllIRQDetails(), isAffineTo(cpu))
+}
+
+// isAffineTo returns a predicate reporting whether the effective CPU
+// affinities of IRQ details include the specified CPU.
+func isAffineTo(cpu uint) func(IRQDetails) bool {
+	cpuList := cpus.List{{cpu, cpu}}
+	return func(details IRQDetails) bool {
+		return details.Affinities.IsOverlapping(cpuList)
+	}
+}
+
+// filterDetails returns an iterator looping over only those IRQ details
+// produced by the specified iterator that satisfy the specified predicate.
+func filterDetails(it iter.Seq[IRQDetails], pred func(IRQDetails) bool) iter.Seq[IRQDetails] {
+	return func(yield func(IRQDetails) bool) {
+		for details := range it {
+			if !pred(details) {
+				continue
+			}
+			if !yield(details) {
+				return
+			}
+		}
+	}
+}
+
+// hasAction reports whether at least one of the comma-separated actions
+// satisfies the specified predicate.
+func hasAction(actions string, pred func(string) bool) bool {
+	for actions != "" {
+		action, rest, _ := strings.Cut(actions, ",")
+		if pred(action) {
+			return true
+		}
+		actions = rest
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("filtering IRQ details", func() {
+
+	It("checks individual actions", func() {
+		isEth0 := func(action string) bool { return action == "eth0" }
+		Expect(hasAction("", isEth0)).To(BeFalse())
+		Expect(hasAction("eth0-rx-1", isEth0)).To(BeFalse())
+		Expect(hasAction("eth0", isEth0)).To(BeTrue())
+		Expect(hasAction("eth0-rx-1,eth0", isEth0)).To(BeTrue())
+		Expect(hasAction("foo,eth0,bar", isEth0)).To(BeTrue())
+	})
+
+	It("filters details", func() {
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			func(details IRQDetails) bool {
+				return hasAction(details.Actions, func(action string) bool {
+					return strings.Contains(action, "ba")
+				})
+			})).To(ConsistOf(
+			HaveField("Num", uint(42)),
+			HaveField("Num", uint(43))))
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			func(details IRQDetails) bool {
+				return hasAction(details.Actions, func(action string) bool {
+					return action == "bar"
+				})
+			})).To(ConsistOf(
+			HaveField("Num", uint(42))))
+	})
+
+	It("filters MSI details", func() {
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			IRQDetails.IsMSI)).To(ConsistOf(HaveField("Num", uint(42))))
+		Expect(AllMSIDetails()).To(HaveEach(HaveField("ChipName", ContainSubstring("MSI"))))
+	})
+
+	It("filters shared details", func() {
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			IRQDetails.IsShared)).To(ConsistOf(HaveField("Num", uint(42))))
+		Expect(AllSharedDetails()).To(HaveEach(HaveField("Actions", ContainSubstring(","))))
+	})
+
+	It("filters wakeup details", func() {
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			IRQDetails.IsWakeup)).To(ConsistOf(HaveField("Num", uint(42))))
+		Expect(AllWakeupDetails()).To(HaveEach(HaveField("Wakeup", BeTrue())))
+	})
+
+	It("filters details by CPU affinity", func() {
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			isAffineTo(2))).To(ConsistOf(
+			HaveField("Num", uint(42)),
+			HaveField("Num", uint(43)),
+			HaveField("Num", uint(46)),
+			HaveField("Num", uint(49))))
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			isAffineTo(42))).To(ConsistOf(HaveField("Num", uint(42))))
+		Expect(filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			isAffineTo(666))).To(BeEmpty())
+		for details := range AllIRQDetails() {
+			if len(details.Affinities) == 0 {
+				continue
+			}
+			cpu, _ := details.Affinities.Remove()
+			Expect(IRQsAffineTo(cpu)).To(ContainElement(HaveField("Num", details.Num)))
+			break
+		}
+	})
+
+	It("stops the yield when told", func() {
+		items := 0
+		for range filterDetails(allIRQDetails(context.Background(), "./testdata/mixed"),
+			func(IRQDetails) bool { return true }) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("filters real IRQ details", func() {
+		var action string
+		for details := range AllIRQDetails() {
+			action, _, _ = strings.Cut(details.Actions, ",")
+			if action != "" {
+				break
+			}
+		}
+		Expect(action).NotTo(BeEmpty())
+		Expect(DetailsForAction(action)).NotTo(BeEmpty())
+		Expect(DetailsForExactAction(action)).To(HaveEach(
+			HaveField("Actions", ContainSubstring(action))))
+	})
+
+})
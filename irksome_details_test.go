@@ -15,6 +15,16 @@
 package irks
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
 	"github.com/thediveo/cpus"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -25,27 +35,300 @@ import (
 var _ = Describe("irksome details", func() {
 
 	It("returns nothing then there are errors", func() {
-		Expect(allIRQDetails("./testdata/non-existing")).To(BeEmpty())
+		Expect(allIRQDetails(context.Background(), "./testdata/non-existing")).To(BeEmpty())
+
+	})
+
+	It("splits actions", func() {
+		Expect(IRQDetails{}.ActionList()).To(And(Not(BeNil()), BeEmpty()))
+		Expect(IRQDetails{Actions: "foo"}.ActionList()).To(HaveExactElements("foo"))
+		Expect(IRQDetails{Actions: "foo,bar"}.ActionList()).To(HaveExactElements("foo", "bar"))
+	})
 
+	It("iterates over actions", func() {
+		Expect(slices.Collect(IRQDetails{}.EachAction)).To(BeEmpty())
+		Expect(slices.Collect(IRQDetails{Actions: "foo"}.EachAction)).To(HaveExactElements("foo"))
+		Expect(slices.Collect(IRQDetails{Actions: "foo,bar,"}.EachAction)).To(HaveExactElements("foo", "bar"))
+		items := 0
+		for range (IRQDetails{Actions: "foo,bar"}).EachAction {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("detects MSI interrupts", func() {
+		Expect(IRQDetails{}.IsMSI()).To(BeFalse())
+		Expect(IRQDetails{ChipName: "IR-IO-APIC"}.IsMSI()).To(BeFalse())
+		Expect(IRQDetails{ChipName: "IR-PCI-MSI-0000:00:14.0"}.IsMSI()).To(BeTrue())
+		Expect(IRQDetails{ChipName: "IR-PCI-MSIX-0000:00:1f.6"}.IsMSI()).To(BeTrue())
+	})
+
+	It("detects per-CPU flow handlers", func() {
+		Expect(isPerCPUFlow("")).To(BeFalse())
+		Expect(isPerCPUFlow("edge")).To(BeFalse())
+		Expect(isPerCPUFlow("percpu")).To(BeTrue())
+		Expect(isPerCPUFlow("percpu_devid")).To(BeTrue())
+	})
+
+	It("detects shared interrupts", func() {
+		Expect(IRQDetails{}.IsShared()).To(BeFalse())
+		Expect(IRQDetails{Actions: "foo"}.IsShared()).To(BeFalse())
+		Expect(IRQDetails{Actions: "foo,"}.IsShared()).To(BeFalse())
+		Expect(IRQDetails{Actions: ",foo"}.IsShared()).To(BeFalse())
+		Expect(IRQDetails{Actions: "foo,bar"}.IsShared()).To(BeTrue())
+		Expect(IRQDetails{Actions: "foo,bar,"}.IsShared()).To(BeTrue())
+	})
+
+	It("detects wakeup interrupts", func() {
+		Expect(IRQDetails{}.IsWakeup()).To(BeFalse())
+		Expect(IRQDetails{Wakeup: true}.IsWakeup()).To(BeTrue())
 	})
 
 	It("returns correct details", func() {
-		Expect(allIRQDetails("./testdata/mixed")).To(ConsistOf(
+		Expect(allIRQDetails(context.Background(), "./testdata/mixed")).To(ConsistOf(
+			IRQDetails{
+				Num:                  42,
+				Actions:              "foo,bar",
+				Affinities:           Successful(cpus.NewList([]byte("1-3,42"))),
+				ConfiguredAffinities: Successful(cpus.NewList([]byte("0-63"))),
+				AffinityHint:         Successful(cpus.NewList([]byte("1-2"))),
+				ChipName:             "IR-PCI-MSIX-0000:00:1f.6",
+				HwIRQ:                524288,
+				FlowName:             "edge",
+				TriggerType:          "edge",
+				Wakeup:               true,
+				Node:                 1,
+			},
+			IRQDetails{
+				Num:                  43,
+				Actions:              "baz",
+				Affinities:           Successful(cpus.NewList([]byte("0-8,15"))),
+				ConfiguredAffinities: Successful(cpus.NewList([]byte("0-3"))),
+				TriggerType:          "level",
+				Node:                 -1,
+			},
 			IRQDetails{
-				Num:        42,
-				Actions:    "foo,bar",
-				Affinities: Successful(cpus.NewList([]byte("1-3,42"))),
+				Num:        45,
+				Affinities: Successful(cpus.NewList([]byte("7"))),
+				Node:       -1,
 			},
 			IRQDetails{
-				Num:        43,
-				Actions:    "baz",
-				Affinities: Successful(cpus.NewList([]byte("0-8,15"))),
+				Num:         46,
+				Affinities:  Successful(cpus.NewList([]byte("2"))),
+				TriggerType: "edge",
+				Node:        -1,
+			},
+			IRQDetails{
+				Num:                  48,
+				Actions:              "qux",
+				Affinities:           Successful(cpus.NewList([]byte("0-1"))),
+				ConfiguredAffinities: Successful(cpus.NewList([]byte("0-1"))),
+				Node:                 -1,
+			},
+			IRQDetails{
+				Num:        49,
+				Actions:    "arch_timer",
+				Affinities: Successful(cpus.NewList([]byte("0-3"))),
+				FlowName:   "percpu_devid",
+				Node:       -1,
+				PerCPU:     true,
+			},
+			IRQDetails{
+				Num:     667,
+				Actions: "foo",
+				Node:    -1,
+			},
+			IRQDetails{
+				Num:     668,
+				Actions: "foo",
+				Node:    -1,
+			}))
+	})
+
+	It("falls back to /proc/irq when /sys/kernel/irq is unavailable", func() {
+		Expect(allIRQDetails(context.Background(), "./testdata/procirq-only")).To(ConsistOf(
+			IRQDetails{
+				Num:        5,
+				Affinities: Successful(cpus.NewList([]byte("0-1"))),
+				Node:       1,
+			},
+			IRQDetails{
+				Num:        6,
+				Affinities: Successful(cpus.NewList([]byte("3"))),
+				Node:       -1,
+			}))
+		details, ok := detailsFor("./testdata/procirq-only", 5)
+		Expect(ok).To(BeTrue())
+		Expect(details.Affinities).To(Equal(Successful(cpus.NewList([]byte("0-1")))))
+		_, ok = detailsFor("./testdata/mixed", 47)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns sorted details", func() {
+		Expect(sortedIRQDetails(allIRQDetails(context.Background(), "./testdata/mixed"))).To(
+			HaveExactElements(
+				HaveField("Num", uint(42)),
+				HaveField("Num", uint(43)),
+				HaveField("Num", uint(45)),
+				HaveField("Num", uint(46)),
+				HaveField("Num", uint(48)),
+				HaveField("Num", uint(49)),
+				HaveField("Num", uint(667)),
+				HaveField("Num", uint(668))))
+		items := 0
+		for range sortedIRQDetails(allIRQDetails(context.Background(), "./testdata/mixed")) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+		Expect(slices.IsSortedFunc(slices.Collect(AllIRQDetailsSorted()),
+			func(a, b IRQDetails) int { return cmp.Compare(a.Num, b.Num) })).To(BeTrue())
+	})
+
+	It("returns the details of a single IRQ", func() {
+		details, ok := detailsFor("./testdata/mixed", 43)
+		Expect(ok).To(BeTrue())
+		Expect(details).To(Equal(IRQDetails{
+			Num:                  43,
+			Actions:              "baz",
+			Affinities:           Successful(cpus.NewList([]byte("0-8,15"))),
+			ConfiguredAffinities: Successful(cpus.NewList([]byte("0-3"))),
+			TriggerType:          "level",
+			Node:                 -1,
+		}))
+
+		_, ok = detailsFor("./testdata/mixed", 1)
+		Expect(ok).To(BeFalse())
+		_, ok = detailsFor("./testdata/mixed", 444)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("skips allocated, but unrequested IRQs", func() {
+		Expect(allIRQDetails(context.Background(), "./testdata/mixed")).NotTo(
+			ContainElement(HaveField("Num", uint(444))))
+		Expect(allIRQDetails(context.Background(), "./testdata/sysirq-only")).To(ConsistOf(
+			IRQDetails{
+				Num:     7,
+				Actions: "foo",
+				Node:    -1,
 			}))
 	})
 
+	It("keeps IRQs with empty or malformed effective affinities", func() {
+		details, ok := detailsFor("./testdata/mixed", 667)
+		Expect(ok).To(BeTrue())
+		Expect(details.Actions).To(Equal("foo"))
+		Expect(details.Affinities).To(BeEmpty())
+		details, ok = detailsFor("./testdata/mixed", 668)
+		Expect(ok).To(BeTrue())
+		Expect(details.Affinities).To(BeEmpty())
+	})
+
+	It("reads actions well over 512 bytes", func() {
+		actions := make([]string, 0, 256)
+		for idx := range cap(actions) {
+			actions = append(actions, fmt.Sprintf("eth0-TxRx-%d", idx))
+		}
+		root := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, syskernelirqPath, "42"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, syskernelirqPath, "42", actionsNode),
+			[]byte(strings.Join(actions, ",")+"\n"), 0o644)).To(Succeed())
+
+		details, ok := detailsFor(root, 42)
+		Expect(ok).To(BeTrue())
+		Expect(len(details.Actions)).To(BeNumerically(">", 6*512))
+		Expect(details.ActionList()).To(Equal(actions))
+
+		// Reading again into the grown buffer must not need more allocations
+		// than reading a small file.
+		path := filepath.Join(root, syskernelirqPath, "42", actionsNode)
+		buffer, ok := fafPseudoFS.readFile(path, nil)
+		Expect(ok).To(BeTrue())
+		largeAllocs := testing.AllocsPerRun(10, func() {
+			buffer, _ = fafPseudoFS.readFile(path, buffer)
+		})
+		Expect(string(buffer)).To(Equal(strings.Join(actions, ",") + "\n"))
+		smallPath := "./testdata/mixed/sys/kernel/irq/48/actions"
+		smallBuffer, ok := fafPseudoFS.readFile(smallPath, nil)
+		Expect(ok).To(BeTrue())
+		smallAllocs := testing.AllocsPerRun(10, func() {
+			smallBuffer, _ = fafPseudoFS.readFile(smallPath, smallBuffer)
+		})
+		Expect(largeAllocs).To(BeNumerically("<=", smallAllocs))
+	})
+
+	It("returns the details of only the requested IRQs", func() {
+		Expect(detailsForNums("./testdata/mixed", []uint{1, 42, 42, 45, 667})).To(
+			HaveExactElements(
+				HaveField("Num", uint(42)),
+				HaveField("Num", uint(45)),
+				HaveField("Num", uint(667))))
+		Expect(detailsForNums("./testdata/mixed", nil)).To(BeEmpty())
+		items := 0
+		for range detailsForNums("./testdata/mixed", []uint{42, 43}) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+		Expect(DetailsForNums([]uint{^uint(0)})).To(BeEmpty())
+	})
+
+	It("limits the number of IRQs scanned", func() {
+		Expect(limitedIRQDetails("./testdata/mixed", 0)).To(BeEmpty())
+		Expect(limitedIRQDetails("./testdata/mixed", -1)).To(BeEmpty())
+		Expect(limitedIRQDetails("./testdata/mixed", 1000)).To(HaveLen(8))
+
+		// A map-based file system lists directories in sorted name order, so
+		// we know exactly which IRQs get scanned: "1", "10", "2", "3", where
+		// IRQ 2 lacks details, yet counts against the limit.
+		fsys := fstest.MapFS{
+			"sys/kernel/irq/1/actions":  {Data: []byte("foo\n")},
+			"sys/kernel/irq/10/actions": {Data: []byte("bar\n")},
+			"sys/kernel/irq/2/hwirq":    {Data: []byte("2\n")},
+			"sys/kernel/irq/3/actions":  {Data: []byte("baz\n")},
+		}
+		limited := func(n int) []uint {
+			nums := []uint{}
+			for details := range irqDetailsFrom(context.Background(), fsPseudoFS(fsys), "", n) {
+				nums = append(nums, details.Num)
+			}
+			return nums
+		}
+		Expect(limited(0)).To(BeEmpty())
+		Expect(limited(1)).To(HaveExactElements(uint(1)))
+		Expect(limited(2)).To(HaveExactElements(uint(1), uint(10)))
+		Expect(limited(3)).To(HaveExactElements(uint(1), uint(10)))
+		Expect(limited(4)).To(HaveExactElements(uint(1), uint(10), uint(3)))
+		Expect(limited(-1)).To(HaveExactElements(uint(1), uint(10), uint(3)))
+		Expect(limitedIRQDetails("./testdata/procirq-only", 1)).To(HaveLen(1))
+		items := 0
+		for range limitedIRQDetails("./testdata/mixed", 2) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+		Expect(len(slices.Collect(AllIRQDetailsLimit(1)))).To(BeNumerically("<=", 1))
+	})
+
+	It("stops when the context gets cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(allIRQDetails(ctx, "./testdata/mixed")).To(BeEmpty())
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		counts := 0
+		for range allIRQDetails(ctx, "./testdata/mixed") {
+			counts++
+			cancel()
+		}
+		Expect(counts).To(Equal(1))
+	})
+
 	It("aborts iterator", func() {
 		counts := 0
-		for range allIRQDetails("./testdata/mixed") {
+		for range allIRQDetails(context.Background(), "./testdata/mixed") {
 			counts++
 			break
 		}
@@ -61,10 +344,21 @@ var _ = Describe("irksome details", func() {
 		for irqdetail := range AllIRQDetails() {
 			counts++
 			Expect(irqnums).To(HaveKey(irqdetail.Num))
-			Expect(irqdetail.Actions).NotTo(BeEmpty())
 			Expect(irqdetail.Affinities).NotTo(BeEmpty())
 		}
 		Expect(counts).NotTo(BeZero())
 	})
 
+	It("reads the real details of a single IRQ", func() {
+		for irqdetail := range AllIRQDetails() {
+			details, ok := DetailsFor(irqdetail.Num)
+			Expect(ok).To(BeTrue())
+			Expect(details.Num).To(Equal(irqdetail.Num))
+			Expect(details.Actions).To(Equal(irqdetail.Actions))
+			break
+		}
+		_, ok := DetailsFor(^uint(0))
+		Expect(ok).To(BeFalse())
+	})
+
 })
@@ -0,0 +1,96 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"context"
+	"iter"
+	"strconv"
+
+	"github.com/thediveo/cpus"
+)
+
+// FullIRQ combines the per-CPU interrupt counters of a particular IRQ with its
+// actions and effective CPU affinities. As with [IRQ], the counters are valid
+// only for the duration of the yield call producing this IRQ data and will
+// then be reused/overwritten afterwards.
+//
+// AffinityMissing flags an inconsistency where an IRQ has fired and its
+// details are available, but its effective CPU affinities are empty, such as
+// when they cannot be parsed or due to kernel quirks. Downstream tooling then
+// can decide whether to trust the affinity data instead of silently getting
+// empty affinities. IRQs without any details are never flagged.
+type FullIRQ struct {
+	IRQ
+	Actions         string    // list of IRQ actions, if available
+	Affinities      cpus.List // effective CPU(s) affinities, if available
+	AffinityMissing bool      // nonzero counters, yet no affinities
+}
+
+// String returns a textual representation of this IRQ with its per-CPU
+// counters, actions, and effective CPU affinities, such as “IRQ 42: CPU0=1
+// CPU1=2 actions="foo,bar" affinities=1-3”. IRQs missing their affinities
+// additionally get marked as “affinity-missing”.
+func (i FullIRQ) String() string {
+	s := i.IRQ.String() +
+		" actions=" + strconv.Quote(i.Actions) +
+		" affinities=" + i.Affinities.String()
+	if i.AffinityMissing {
+		s += " affinity-missing"
+	}
+	return s
+}
+
+// AllIRQs returns a single-use iterator that loops over all
+// (non-architecture-specific) IRQs, producing their per-CPU counters together
+// with their actions and effective CPU affinities. The counters are read from
+// “/proc/interrupts”, whereas the actions and affinities are read from
+// “/sys/kernel/irq/#/” and “/proc/irq/#/”, and then merged on the IRQ number.
+// IRQs without details have empty actions and affinities.
+//
+// Please note that the counters and thus the CPUs list only cover the CPUs that
+// are currently online, as reported by “/proc/interrupts”. In contrast,
+// “/sys/kernel/irq/#/per_cpu_count” covers all CPUs, online as well as
+// offline. Also, the effective CPU affinities may well contain CPUs that are
+// not part of the CPUs list.
+func AllIRQs() iter.Seq[FullIRQ] {
+	return allIRQs("")
+}
+
+// allIRQs returns an iterator looping over the IRQs with their counters and
+// details, with the pseudo files located beneath the specified root.
+func allIRQs(root string) iter.Seq[FullIRQ] {
+	return func(yield func(FullIRQ) bool) {
+		// As the details come in no particular order, we need to first gather
+		// all of them before we can merge them into the counters.
+		details := map[uint]IRQDetails{}
+		for detail := range allIRQDetails(context.Background(), root) {
+			details[detail.Num] = detail
+		}
+		for irq := range AllCountersFrom(root) {
+			// Only flag missing affinities for IRQs we have details about, as
+			// IRQs without any details lack affinities for other reasons.
+			detail, ok := details[irq.Num]
+			if !yield(FullIRQ{
+				IRQ:             irq,
+				Actions:         detail.Actions,
+				Affinities:      detail.Affinities,
+				AffinityMissing: ok && len(detail.Affinities) == 0 && irq.Total() > 0,
+			}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"fmt"
+
+	"github.com/thediveo/cpus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("full IRQs", func() {
+
+	It("returns nothing when there are errors", func() {
+		Expect(allIRQs("./testdata/non-existing")).To(BeEmpty())
+	})
+
+	It("merges counters with details", func() {
+		irqs := []FullIRQ{}
+		for irq := range allIRQs("./testdata/mixed") {
+			irq.IRQ = irq.IRQ.Clone()
+			irqs = append(irqs, irq)
+		}
+		Expect(irqs).To(HaveExactElements(
+			And(HaveField("Num", uint(0)), HaveField("Actions", ""), HaveField("Affinities", BeEmpty())),
+			HaveField("Num", uint(1)),
+			HaveField("Num", uint(8)),
+			And(HaveField("Num", uint(42)),
+				HaveField("Counters", HaveExactElements(uint64(0), uint64(1234), uint64(0), uint64(56))),
+				HaveField("Actions", "foo,bar"),
+				HaveField("Affinities", Successful(cpus.NewList([]byte("1-3,42"))))),
+			And(HaveField("Num", uint(43)),
+				HaveField("Actions", "baz"),
+				HaveField("Affinities", Successful(cpus.NewList([]byte("0-8,15")))))))
+	})
+
+	It("flags missing affinities of IRQs that fired", func() {
+		Expect(allIRQs("./testdata/affinity-missing")).To(HaveExactElements(
+			And(HaveField("Num", uint(10)), HaveField("Actions", "foo"),
+				HaveField("Affinities", BeEmpty()), HaveField("AffinityMissing", BeTrue())),
+			And(HaveField("Num", uint(11)), HaveField("Affinities", Successful(cpus.NewList([]byte("0")))),
+				HaveField("AffinityMissing", BeFalse())),
+			And(HaveField("Num", uint(12)), HaveField("Affinities", BeEmpty()),
+				HaveField("AffinityMissing", BeFalse()))))
+		for irq := range allIRQs("./testdata/mixed") {
+			Expect(irq.AffinityMissing).To(Equal(irq.Actions != "" && len(irq.Affinities) == 0 && irq.Total() > 0),
+				"IRQ %d", irq.Num)
+			if irq.Num == 0 || irq.Num == 42 || irq.Num == 43 {
+				Expect(irq.AffinityMissing).To(BeFalse(), "IRQ %d", irq.Num)
+			}
+		}
+	})
+
+	It("prints its own fields", func() {
+		Expect(fmt.Sprint(FullIRQ{
+			IRQ:        IRQ{Num: 42, CPUs: CPUList{1, 2}, Counters: []uint64{3, 4}},
+			Actions:    "foo,bar",
+			Affinities: Successful(cpus.NewList([]byte("1-3"))),
+		})).To(Equal(`IRQ 42: CPU1=3 CPU2=4 actions="foo,bar" affinities=1-3`))
+		Expect(fmt.Sprint(FullIRQ{
+			IRQ:             IRQ{Num: 10},
+			Actions:         "foo",
+			AffinityMissing: true,
+		})).To(Equal(`IRQ 10: actions="foo" affinities= affinity-missing`))
+	})
+
+	It("stops the yield when told", func() {
+		items := 0
+		for range allIRQs("./testdata/mixed") {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("reads real IRQs", func() {
+		Expect(AllIRQs()).NotTo(BeEmpty())
+	})
+
+})
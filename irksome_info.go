@@ -0,0 +1,204 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"strconv"
+
+	"github.com/thediveo/faf"
+)
+
+// IRQInfo holds the per-CPU interrupt counters for a particular IRQ, together
+// with the additional information about this IRQ from the trailing columns of
+// “/proc/interrupts”. As with [IRQ], the counters are valid only for the
+// duration of the yield call producing this IRQ data.
+//
+// The trailing columns are, in this order:
+//   - the name of the IRQ chip, or “None” if there is no chip;
+//   - the hardware IRQ number within its IRQ domain, if there is a domain;
+//   - the trigger type “Level” or “Edge”, but only if the kernel has been
+//     compiled with CONFIG_GENERIC_IRQ_SHOW_LEVEL;
+//   - the descriptive name (such as the flow handler name “edge”), as well as
+//     the actions, if any.
+//
+// For convenience, Label contains all the trailing text following the
+// counters, with leading and trailing spaces removed, but internal spaces
+// preserved.
+type IRQInfo struct {
+	IRQ
+	ChipName string // name of the IRQ chip, or "None".
+	HwIRQ    uint64 // hardware IRQ number within the IRQ domain, if any.
+	Trigger  string // "Level" or "Edge", if shown by the kernel.
+	Name     string // descriptive name and actions, up to the end of line.
+	Label    string // complete trailing text following the counters.
+}
+
+// String returns a textual representation of this IRQ with its per-CPU
+// counters and the information from the trailing columns, such as “IRQ 42:
+// CPU0=1 CPU1=2 chip="IR-PCI-MSI" hwirq=0 trigger="" name="edge foo"”.
+func (i IRQInfo) String() string {
+	return i.IRQ.String() +
+		" chip=" + strconv.Quote(i.ChipName) +
+		" hwirq=" + strconv.FormatUint(i.HwIRQ, 10) +
+		" trigger=" + strconv.Quote(i.Trigger) +
+		" name=" + strconv.Quote(i.Name)
+}
+
+// AllCountersWithInfo returns a single-use iterator that loops over
+// “/proc/interrupts” producing all (non-architecture-specific) IRQs, including
+// the information from the trailing columns about the IRQ chip, hardware IRQ
+// number, trigger, and descriptive name.
+//
+// This avoids the many pseudo file reads [AllIRQDetails] needs when only the
+// chip and descriptive names are of interest.
+func AllCountersWithInfo() iter.Seq[IRQInfo] {
+	return AllCountersWithInfoFrom("")
+}
+
+// AllCountersWithInfoFrom returns a single-use iterator that loops over
+// “/proc/interrupts” located beneath the specified root, producing all
+// (non-architecture-specific) IRQs including the information from the trailing
+// columns. An empty root refers to the root of the file system, so this then is
+// the same as [AllCountersWithInfo].
+func AllCountersWithInfoFrom(root string) iter.Seq[IRQInfo] {
+	return func(yield func(IRQInfo) bool) {
+		f, err := openProcInterrupts(root)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		iterateAllCountersWithInfo(f, yield)
+	}
+}
+
+// allCountersWithInfo returns an iterator looping over the IRQs with their
+// per-CPU counters and trailing column information, based on the information
+// in “/proc/interrupts” format and produced by the specified reader.
+func allCountersWithInfo(r io.Reader) iter.Seq[IRQInfo] {
+	return func(yield func(IRQInfo) bool) {
+		iterateAllCountersWithInfo(r, yield)
+	}
+}
+
+func iterateAllCountersWithInfo(r io.Reader, yield func(IRQInfo) bool) {
+	sc := newProcInterruptsScanner(r)
+	if !sc.Scan() {
+		return
+	}
+	cpus := cpuListFromProcInterrupts(sc.Bytes())
+	numCPUs := len(cpus)
+	if numCPUs == 0 {
+		return
+	}
+	info := IRQInfo{
+		IRQ: IRQ{
+			CPUs:     cpus,
+			Counters: make([]uint64, numCPUs),
+		},
+	}
+nextLine:
+	for sc.Scan() {
+		// Fetch the IRQ number from the beginning of the current text line,
+		// skipping "unnumbered" (architecture specific) IRQs, as well as
+		// malformed or truncated lines.
+		line := sc.Bytes()
+		colon := bytes.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		irqno, ok := faf.ParseUint(skipSpace(line[:colon]))
+		if !ok {
+			continue
+		}
+		info.Num = uint(irqno)
+
+		rest := line[colon+1:]
+		for idx := 0; idx < numCPUs; idx++ {
+			var field []byte
+			field, rest = nextField(rest)
+			count, ok := faf.ParseUint(field)
+			if !ok {
+				continue nextLine
+			}
+			info.Counters[idx] = count
+		}
+		// Skip lines having more counter columns than the header has CPUs,
+		// same as the plain counter iterators do.
+		if excessCounter(faf.NewBytestring(rest)) {
+			continue
+		}
+		parseInfoColumns(rest, &info)
+
+		if !yield(info) {
+			return
+		}
+	}
+}
+
+// parseInfoColumns parses the trailing columns following the per-CPU counters
+// of a “/proc/interrupts” IRQ line, please see also the kernel's
+// [show_interrupts]. The chip name is always taken as a single
+// whitespace-delimited token, so that chip names containing dots and dashes,
+// such as “9030000.pl061” on arm64, stay intact.
+//
+// The trigger type relies on show_interrupts emitting the columns strictly in
+// the order chip, hardware IRQ number, trigger type, and then the descriptive
+// name prefixed by “-”. Thus, only a standalone, capitalized “Level” or “Edge”
+// token directly after the chip name and optional hardware IRQ number is taken
+// as the trigger type, whereas a descriptive name such as “-edge” or
+// “2-level” never is. The only remaining ambiguity is an IRQ without
+// descriptive name that has an action literally named “Level” or “Edge” on a
+// kernel without CONFIG_GENERIC_IRQ_SHOW_LEVEL; this action then gets
+// mistaken as the trigger type.
+//
+// [show_interrupts]: https://elixir.bootlin.com/linux/v6.12/source/kernel/irq/proc.c#L463
+func parseInfoColumns(b []byte, info *IRQInfo) {
+	info.Label = string(bytes.Trim(b, " "))
+
+	var field []byte
+	field, b = nextField(b)
+	info.ChipName = string(field)
+
+	// The hardware IRQ number is only present if the IRQ has a domain. If the
+	// IRQ additionally has a descriptive name, then the kernel appends it
+	// directly, separated by "-", such as in "2-edge".
+	info.HwIRQ = 0
+	b = skipSpace(b)
+	digits := 0
+	for digits < len(b) && b[digits] >= '0' && b[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && (digits == len(b) || b[digits] == ' ' || b[digits] == '-') {
+		if hwirq, ok := faf.ParseUint(b[:digits]); ok {
+			info.HwIRQ = hwirq
+			b = b[digits:]
+		}
+	}
+
+	info.Trigger = ""
+	if field, rest := nextField(b); string(field) == "Level" || string(field) == "Edge" {
+		info.Trigger = string(field)
+		b = rest
+	}
+
+	b = skipSpace(b)
+	if len(b) > 0 && b[0] == '-' {
+		b = b[1:]
+	}
+	info.Name = string(bytes.TrimRight(b, " "))
+}
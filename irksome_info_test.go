@@ -0,0 +1,165 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("IRQ counters with info", func() {
+
+	It("yields nothing for invalid data", func() {
+		Expect(allCountersWithInfo(strings.NewReader(""))).To(BeEmpty())
+		Expect(allCountersWithInfo(strings.NewReader(" CPU1 CPU2\n 1: 2"))).To(BeEmpty())
+		Expect(allCountersWithInfo(strings.NewReader(" CPU1 CPU2\n 1: 2 abc"))).To(BeEmpty())
+	})
+
+	It("skips malformed lines", func() {
+		Expect(allCountersWithInfo(strings.NewReader(" CPU1 CPU2\n 1: 2\n FOO: 1 2\n 3: 4 5 None\n"))).To(
+			HaveExactElements(And(HaveField("Num", uint(3)), HaveField("ChipName", "None"))))
+	})
+
+	It("parses the trailing columns", func() {
+		f := Successful(os.Open("./testdata/mixed/proc/interrupts"))
+		defer f.Close()
+		infos := []IRQInfo{}
+		for info := range allCountersWithInfo(f) {
+			info.Counters = nil // transient, so don't keep
+			infos = append(infos, info)
+		}
+		Expect(infos).To(HaveExactElements(
+			And(HaveField("Num", uint(0)),
+				HaveField("ChipName", "IR-IO-APIC"), HaveField("HwIRQ", uint64(2)),
+				HaveField("Trigger", ""), HaveField("Name", "edge      timer"),
+				HaveField("Label", "IR-IO-APIC    2-edge      timer")),
+			HaveField("Num", uint(1)),
+			HaveField("Num", uint(8)),
+			And(HaveField("Num", uint(42)),
+				HaveField("ChipName", "IR-PCI-MSIX-0000:00:1f.6"), HaveField("HwIRQ", uint64(0)),
+				HaveField("Name", "edge      foo, bar"),
+				HaveField("Label", "IR-PCI-MSIX-0000:00:1f.6    0-edge      foo, bar")),
+			HaveField("Num", uint(43))))
+	})
+
+	It("parses the trailing columns on arm64", func() {
+		f := Successful(os.Open("./testdata/arm64/proc/interrupts"))
+		defer f.Close()
+		infos := []IRQInfo{}
+		for info := range allCountersWithInfo(f) {
+			info.Counters = nil // transient, so don't keep
+			infos = append(infos, info)
+		}
+		Expect(infos).To(HaveExactElements(
+			And(HaveField("Num", uint(10)),
+				HaveField("ChipName", "GICv3"), HaveField("HwIRQ", uint64(27)),
+				HaveField("Trigger", "Level"), HaveField("Name", "arch_timer")),
+			And(HaveField("Num", uint(12)),
+				HaveField("ChipName", "GICv3"), HaveField("HwIRQ", uint64(23)),
+				HaveField("Trigger", "Level"), HaveField("Name", "arm-pmu")),
+			And(HaveField("Num", uint(13)),
+				HaveField("ChipName", "GICv3"), HaveField("HwIRQ", uint64(33)),
+				HaveField("Trigger", "Level"), HaveField("Name", "uart-pl011")),
+			And(HaveField("Num", uint(50)),
+				HaveField("ChipName", "ITS-MSI"), HaveField("HwIRQ", uint64(524288)),
+				HaveField("Trigger", "Edge"), HaveField("Name", "nvme0q0")),
+			And(HaveField("Num", uint(51)),
+				HaveField("ChipName", "ITS-MSI"), HaveField("HwIRQ", uint64(524289)),
+				HaveField("Trigger", "Edge"), HaveField("Name", "eth0-TxRx-0")),
+			And(HaveField("Num", uint(70)),
+				HaveField("ChipName", "9030000.pl061"), HaveField("HwIRQ", uint64(3)),
+				HaveField("Trigger", "Edge"), HaveField("Name", "GPIO Key Poweroff"),
+				HaveField("Label", "9030000.pl061   3 Edge      GPIO Key Poweroff")),
+			And(HaveField("Num", uint(80)),
+				HaveField("ChipName", "mbigen-v2"), HaveField("HwIRQ", uint64(0)),
+				HaveField("Trigger", "Edge"), HaveField("Name", "0-00000000.interrupt-controller"),
+				HaveField("Label", "mbigen-v2   0 Edge      0-00000000.interrupt-controller"))))
+	})
+
+	It("prints its own fields", func() {
+		Expect(fmt.Sprint(IRQInfo{
+			IRQ:      IRQ{Num: 42, CPUs: CPUList{0}, Counters: []uint64{1}},
+			ChipName: "IR-PCI-MSI",
+			HwIRQ:    0,
+			Trigger:  "Edge",
+			Name:     "foo, bar",
+		})).To(Equal(`IRQ 42: CPU0=1 chip="IR-PCI-MSI" hwirq=0 trigger="Edge" name="foo, bar"`))
+	})
+
+	It("skips IRQs with mismatched counter columns", func() {
+		f := Successful(os.Open("./testdata/hotplug/proc/interrupts"))
+		defer f.Close()
+		infos := []IRQInfo{}
+		for info := range allCountersWithInfo(f) {
+			info.Counters = slices.Clone(info.Counters)
+			infos = append(infos, info)
+		}
+		Expect(infos).To(HaveExactElements(
+			And(HaveField("Num", uint(0)), HaveField("ChipName", "IR-IO-APIC")),
+			And(HaveField("Num", uint(42)), HaveField("ChipName", "42-PCI-MSIX-0000:00:1f.6"),
+				HaveField("Counters", HaveExactElements(uint64(0), uint64(1234), uint64(56)))),
+			And(HaveField("Num", uint(44)), HaveField("ChipName", ""))))
+	})
+
+	DescribeTable("parsing trailing columns",
+		func(columns string, chip string, hwirq uint64, trigger, name string) {
+			var info IRQInfo
+			parseInfoColumns([]byte(columns), &info)
+			Expect(info.ChipName).To(Equal(chip))
+			Expect(info.HwIRQ).To(Equal(hwirq))
+			Expect(info.Trigger).To(Equal(trigger))
+			Expect(info.Name).To(Equal(name))
+			Expect(info.Label).To(Equal(strings.Trim(columns, " ")))
+		},
+		Entry("empty columns", "", "", uint64(0), "", ""),
+		Entry("no chip", "  None", "None", uint64(0), "", ""),
+		Entry("x86 with appended name", "  IR-IO-APIC    9-fasteoi   acpi", "IR-IO-APIC", uint64(9), "", "fasteoi   acpi"),
+		Entry("arm64 with trigger", "     GICv3  27 Level     arch_timer", "GICv3", uint64(27), "Level", "arch_timer"),
+		Entry("trigger and dash-prefixed name", "     GICv3  27 Level  -foo  bar ", "GICv3", uint64(27), "Level", "foo  bar"),
+		Entry("no domain", "  dummy      -edge  baz", "dummy", uint64(0), "", "edge  baz"),
+		Entry("non-numeric domain lookalike", "  dummy  42abc", "dummy", uint64(0), "", "42abc"),
+		Entry("chip with dots and dashes", "  d0010000.gpio-bank  5 Edge  sd-cd", "d0010000.gpio-bank", uint64(5), "Edge", "sd-cd"),
+		Entry("trigger without domain", "      None      Edge    ", "None", uint64(0), "Edge", ""),
+		Entry("trigger without domain and dash-prefixed name", "  dummy    Level   -fasteoi  foo", "dummy", uint64(0), "Level", "fasteoi  foo"),
+		Entry("trigger lookalike appended name", "  IR-IO-APIC    9-Level  foo", "IR-IO-APIC", uint64(9), "", "Level  foo"),
+		Entry("lowercase trigger lookalike", "     GICv3  27 level", "GICv3", uint64(27), "", "level"),
+		Entry("trigger lookalike prefix", "     GICv3  27 Edgey  foo", "GICv3", uint64(27), "", "Edgey  foo"),
+		Entry("overflowing hwirq lookalike", "  dummy  123456789012345678901234 foo", "dummy", uint64(0), "", "123456789012345678901234 foo"),
+		Entry("hwirq with appended dotted name", "  dummy  0-00000000.interrupt-controller", "dummy", uint64(0), "", "00000000.interrupt-controller"),
+	)
+
+	It("reads from /proc/interrupts beneath a root", func() {
+		Expect(AllCountersWithInfoFrom("./testdata/mixed")).To(HaveExactElements(
+			And(HaveField("Num", uint(0)), HaveField("ChipName", "IR-IO-APIC")),
+			HaveField("Num", uint(1)),
+			HaveField("Num", uint(8)),
+			HaveField("Num", uint(42)),
+			And(HaveField("Num", uint(43)), HaveField("Name", "edge      baz"))))
+		Expect(AllCountersWithInfoFrom("./testdata/non-existing")).To(BeEmpty())
+	})
+
+	It("reads something sensible from /proc/interrupts", func() {
+		for info := range AllCountersWithInfo() {
+			Expect(info.ChipName).NotTo(BeEmpty())
+		}
+	})
+
+})
@@ -0,0 +1,194 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"io"
+	"iter"
+
+	"github.com/thediveo/faf"
+)
+
+// NamedInterrupt holds the per-CPU interrupt counters for an
+// architecture-specific interrupt, such as “NMI” or “LOC”. Similar to [IRQ],
+// the counters are valid only for the duration of the yield call producing
+// this interrupt data and will be reused/overwritten afterwards.
+//
+// Please note that some architecture-specific interrupts, such as “ERR” and
+// “MIS” on x86, are system-wide and thus have only a single counter instead of
+// per-CPU counters.
+type NamedInterrupt struct {
+	Name        string   // mnemonic name, such as "NMI".
+	Counters    []uint64 // per-CPU counters, valid during a single iteration, then reused.
+	CPUs        CPUList  // list of the number of the CPUs that are currently online.
+	Description string   // trailing free-text description, if any.
+}
+
+// namedInterruptDescriptions maps the well-known mnemonics of
+// architecture-specific interrupts to human-readable descriptions, as of Linux
+// 6.12. Please see [arch_show_interrupts] for x86 and [arch_show_interrupts
+// (arm64)] for arm64, which names its IPIs by number.
+//
+// [arch_show_interrupts]: https://elixir.bootlin.com/linux/v6.12/source/arch/x86/kernel/irq.c#L61
+// [arch_show_interrupts (arm64)]: https://elixir.bootlin.com/linux/v6.12/source/arch/arm64/kernel/smp.c#L792
+var namedInterruptDescriptions = map[string]string{
+	// x86
+	"NMI": "Non-maskable interrupts",
+	"LOC": "Local timer interrupts",
+	"SPU": "Spurious interrupts",
+	"PMI": "Performance monitoring interrupts",
+	"IWI": "IRQ work interrupts",
+	"RTR": "APIC ICR read retries",
+	"PLT": "Platform interrupts",
+	"RES": "Rescheduling interrupts",
+	"CAL": "Function call interrupts",
+	"TLB": "TLB shootdowns",
+	"TRM": "Thermal event interrupts",
+	"THR": "Threshold APIC interrupts",
+	"DFR": "Deferred Error APIC interrupts",
+	"MCE": "Machine check exceptions",
+	"MCP": "Machine check polls",
+	"HYP": "Hypervisor callback interrupts",
+	"HRE": "Hyper-V reenlightenment interrupts",
+	"HVS": "Hyper-V stimer0 interrupts",
+	"PIN": "Posted-interrupt notification event",
+	"NPI": "Nested posted-interrupt event",
+	"PIW": "Posted-interrupt wakeup event",
+	"PMN": "Posted MSI notification event",
+	"ERR": "APIC error interrupts",
+	"MIS": "Mis-routed IO-APIC interrupts",
+	// arm64
+	"IPI0": "Rescheduling interrupts",
+	"IPI1": "Function call interrupts",
+	"IPI2": "CPU stop interrupts",
+	"IPI3": "CPU stop (for crash dump) interrupts",
+	"IPI4": "Timer broadcast interrupts",
+	"IPI5": "IRQ work interrupts",
+	"Err":  "Error interrupts",
+}
+
+// DescribeNamedInterrupt returns a human-readable description for the
+// specified mnemonic name of an architecture-specific interrupt, such as
+// “Local timer interrupts” for “LOC”. For unknown mnemonics,
+// DescribeNamedInterrupt returns the name itself.
+func DescribeNamedInterrupt(name string) string {
+	if description, ok := namedInterruptDescriptions[name]; ok {
+		return description
+	}
+	return name
+}
+
+// Describe returns a human-readable description of this named interrupt: the
+// description of a well-known mnemonic if available, otherwise the trailing
+// description from “/proc/interrupts”, and finally the name itself.
+func (n NamedInterrupt) Describe() string {
+	if description, ok := namedInterruptDescriptions[n.Name]; ok {
+		return description
+	}
+	if n.Description != "" {
+		return n.Description
+	}
+	return n.Name
+}
+
+// AllNamedCounters returns a single-use iterator that loops over
+// “/proc/interrupts” producing only the architecture-specific interrupts that
+// have alphanumeric names instead of IRQ numbers.
+//
+// The produced interrupt information contains the per-CPU counters for a
+// particular named interrupt, but only for CPUs that are currently online.
+func AllNamedCounters() iter.Seq[NamedInterrupt] {
+	return AllNamedCountersFrom("")
+}
+
+// AllNamedCountersFrom returns a single-use iterator that loops over
+// “/proc/interrupts” located beneath the specified root, producing only the
+// architecture-specific interrupts. An empty root refers to the root of the
+// file system, so this then is the same as [AllNamedCounters].
+func AllNamedCountersFrom(root string) iter.Seq[NamedInterrupt] {
+	return func(yield func(NamedInterrupt) bool) {
+		f, err := openProcInterrupts(root)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		iterateNamedCounters(f, yield)
+	}
+}
+
+// allNamedCounters returns an iterator looping over the architecture-specific
+// interrupts with their per-CPU counters based on the information in
+// “/proc/interrupts” format and produced by the specified reader.
+func allNamedCounters(r io.Reader) iter.Seq[NamedInterrupt] {
+	return func(yield func(NamedInterrupt) bool) {
+		iterateNamedCounters(r, yield)
+	}
+}
+
+func iterateNamedCounters(r io.Reader, yield func(NamedInterrupt) bool) {
+	sc := newProcInterruptsScanner(r)
+	if !sc.Scan() {
+		return
+	}
+	cpus := cpuListFromProcInterrupts(sc.Bytes())
+	numCPUs := len(cpus)
+	if numCPUs == 0 {
+		return
+	}
+	counters := make([]uint64, numCPUs)
+	named := NamedInterrupt{
+		CPUs: cpus,
+	}
+	for sc.Scan() {
+		line := sc.Bytes()
+		colon := bytes.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		// Skip all the numbered IRQs, as these are the domain of the IRQ
+		// counter iterators.
+		name := skipSpace(line[:colon])
+		if len(name) == 0 {
+			continue
+		}
+		if _, ok := faf.ParseUint(name); ok {
+			continue
+		}
+
+		// Consume as many counters as there are, but not more than there are
+		// CPUs online; some architecture-specific interrupts only have a
+		// single system-wide counter.
+		rest := line[colon+1:]
+		numCounters := 0
+		for numCounters < numCPUs {
+			field, remaining := nextField(rest)
+			count, ok := faf.ParseUint(field)
+			if !ok {
+				break
+			}
+			counters[numCounters] = count
+			numCounters++
+			rest = remaining
+		}
+		named.Name = string(name)
+		named.Counters = counters[:numCounters]
+		named.Description = string(bytes.Trim(rest, " "))
+
+		if !yield(named) {
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"iter"
+	"os"
+	"slices"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// safelyCollectNamedInterrupts loops over named interrupts, returning a slice
+// of collected interrupts while ensuring proper copying of transient
+// information to make it permanent.
+func safelyCollectNamedInterrupts(it iter.Seq[NamedInterrupt]) []NamedInterrupt {
+	nameds := []NamedInterrupt{}
+	for named := range it {
+		named.Counters = slices.Clone(named.Counters)
+		nameds = append(nameds, named)
+	}
+	return nameds
+}
+
+var _ = Describe("named interrupts", func() {
+
+	It("yields nothing for invalid data", func() {
+		Expect(allNamedCounters(strings.NewReader(""))).To(BeEmpty())
+		Expect(allNamedCounters(strings.NewReader(" FOO1\n NMI: 1"))).To(BeEmpty())
+	})
+
+	It("yields only named interrupts", func() {
+		nameds := safelyCollectNamedInterrupts(
+			allNamedCounters(strings.NewReader(procInterruptsText)))
+		Expect(nameds).To(HaveExactElements(
+			And(
+				HaveField("Name", "ENEMIH"),
+				HaveField("CPUs", HaveExactElements(uint(1), uint(42), uint(666))),
+				HaveField("Counters", HaveExactElements(uint64(1), uint64(2), uint64(3))),
+				HaveField("Description", "zz"))))
+	})
+
+	It("handles system-wide counters and multi-word descriptions", func() {
+		f := Successful(os.Open("./testdata/mixed/proc/interrupts"))
+		defer f.Close()
+		nameds := safelyCollectNamedInterrupts(allNamedCounters(f))
+		Expect(nameds).To(HaveExactElements(
+			And(
+				HaveField("Name", "NMI"),
+				HaveField("Counters", HaveExactElements(uint64(0), uint64(0), uint64(0), uint64(0))),
+				HaveField("Description", "Non-maskable interrupts")),
+			And(
+				HaveField("Name", "LOC"),
+				HaveField("Counters", HaveExactElements(
+					uint64(123456), uint64(234567), uint64(345678), uint64(456789))),
+				HaveField("Description", "Local timer interrupts")),
+			And(
+				HaveField("Name", "ERR"),
+				HaveField("Counters", HaveExactElements(uint64(0))),
+				HaveField("Description", "")),
+			And(
+				HaveField("Name", "MIS"),
+				HaveField("Counters", HaveExactElements(uint64(0))),
+				HaveField("Description", ""))))
+	})
+
+	It("handles arm64 IPIs", func() {
+		f := Successful(os.Open("./testdata/arm64/proc/interrupts"))
+		defer f.Close()
+		nameds := safelyCollectNamedInterrupts(allNamedCounters(f))
+		Expect(nameds).To(HaveExactElements(
+			And(
+				HaveField("Name", "IPI0"),
+				HaveField("Counters", HaveExactElements(uint64(101), uint64(102), uint64(103), uint64(104))),
+				HaveField("Description", "Rescheduling interrupts")),
+			HaveField("Name", "IPI1"),
+			HaveField("Name", "IPI2"),
+			And(
+				HaveField("Name", "Err"),
+				HaveField("Counters", HaveExactElements(uint64(0))))))
+	})
+
+	It("stops the yield when told", func() {
+		f := Successful(os.Open("./testdata/mixed/proc/interrupts"))
+		defer f.Close()
+		items := 0
+		for range allNamedCounters(f) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("describes well-known and unknown mnemonics", func() {
+		Expect(DescribeNamedInterrupt("LOC")).To(Equal("Local timer interrupts"))
+		Expect(DescribeNamedInterrupt("MIS")).To(Equal("Mis-routed IO-APIC interrupts"))
+		Expect(DescribeNamedInterrupt("IPI0")).To(Equal("Rescheduling interrupts"))
+		Expect(DescribeNamedInterrupt("ENEMIH")).To(Equal("ENEMIH"))
+
+		Expect(NamedInterrupt{Name: "ERR"}.Describe()).To(Equal("APIC error interrupts"))
+		Expect(NamedInterrupt{Name: "ENEMIH", Description: "zz"}.Describe()).To(Equal("zz"))
+		Expect(NamedInterrupt{Name: "ENEMIH"}.Describe()).To(Equal("ENEMIH"))
+	})
+
+	It("reads from /proc/interrupts beneath a root", func() {
+		Expect(AllNamedCountersFrom("./testdata/mixed")).To(HaveExactElements(
+			HaveField("Name", "NMI"),
+			HaveField("Name", "LOC"),
+			HaveField("Name", "ERR"),
+			HaveField("Name", "MIS")))
+		Expect(AllNamedCountersFrom("./testdata/non-existing")).To(BeEmpty())
+	})
+
+	It("reads something sensible from /proc/interrupts", func() {
+		for named := range AllNamedCounters() {
+			Expect(named.Name).NotTo(BeEmpty())
+			Expect(len(named.Counters)).To(BeNumerically("<=", len(named.CPUs)))
+		}
+	})
+
+})
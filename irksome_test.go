@@ -15,6 +15,9 @@
 package irks
 
 import (
+	"bufio"
+	"cmp"
+	"fmt"
 	"iter"
 	"math/rand/v2"
 	"os"
@@ -32,9 +35,7 @@ import (
 func safelyCollectIRQs(it iter.Seq[IRQ]) []IRQ {
 	irqs := []IRQ{}
 	for irq := range it {
-		irq := irq
-		irq.Counters = slices.Clone(irq.Counters)
-		irqs = append(irqs, irq)
+		irqs = append(irqs, irq.Clone())
 	}
 	return irqs
 }
@@ -47,6 +48,39 @@ const procInterruptsText = ` CPU1 CPU42 CPU666
 
 var _ = Describe("irksome", func() {
 
+	It("counts active CPUs and finds the hottest CPU", func() {
+		Expect(IRQ{}.ActiveCPUs()).To(BeZero())
+		cpu, count := IRQ{}.HottestCPU()
+		Expect(cpu).To(BeZero())
+		Expect(count).To(BeZero())
+
+		irq := IRQ{
+			Num:      42,
+			CPUs:     CPUList{1, 42, 666, 667},
+			Counters: []uint64{0, 3, 0, 3},
+		}
+		Expect(irq.ActiveCPUs()).To(Equal(2))
+		cpu, count = irq.HottestCPU()
+		Expect(cpu).To(Equal(uint(42)))
+		Expect(count).To(Equal(uint64(3)))
+
+		irq.Counters = []uint64{0, 0, 0, 0}
+		cpu, count = irq.HottestCPU()
+		Expect(cpu).To(Equal(uint(1)))
+		Expect(count).To(BeZero())
+	})
+
+	It("renders IRQs as text", func() {
+		Expect(IRQ{Num: 42}.String()).To(Equal("IRQ 42:"))
+		Expect(IRQ{
+			Num:      42,
+			CPUs:     CPUList{1, 42, 666},
+			Counters: []uint64{2, 3, 4},
+		}.String()).To(Equal("IRQ 42: CPU1=2 CPU42=3 CPU666=4"))
+		Expect(fmt.Sprintf("%v", IRQ{Num: 1, CPUs: CPUList{0}, Counters: []uint64{7}})).To(
+			Equal("IRQ 1: CPU0=7"))
+	})
+
 	When("determining online CPU numbers", func() {
 
 		It("returns an empty list for malformed lines", func() {
@@ -60,6 +94,72 @@ var _ = Describe("irksome", func() {
 				HaveExactElements(CPUList{1, 42, 666}))
 		})
 
+		It("tolerates a trailing non-CPU column", func() {
+			Expect(cpuListFromProcInterrupts([]byte("  CPU1  CPU42  FOO "))).To(
+				HaveExactElements(CPUList{1, 42}))
+			Expect(cpuListFromProcInterrupts([]byte("  CPU1  CPU42  CPUX"))).To(
+				HaveExactElements(CPUList{1, 42}))
+			Expect(safelyCollectIRQs(allCounters(strings.NewReader(" CPU1 CPU42 garbage\n 1: 2 3 x\n"), nil))).To(
+				HaveExactElements(IRQ{Num: 1, Counters: []uint64{2, 3}, CPUs: CPUList{1, 42}}))
+		})
+
+	})
+
+	When("determining only the online CPUs", func() {
+
+		It("returns the online CPUs", func() {
+			cpus, ok := onlineCPUs("./testdata/mixed")
+			Expect(ok).To(BeTrue())
+			Expect(cpus).To(HaveExactElements(uint(0), uint(1), uint(2), uint(3)))
+		})
+
+		It("reports failure", func() {
+			_, ok := onlineCPUs("./testdata/non-existing")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the online CPUs of this system", func() {
+			cpus, ok := OnlineCPUs()
+			Expect(ok).To(BeTrue())
+			Expect(cpus).NotTo(BeEmpty())
+		})
+
+	})
+
+	When("mapping CPU numbers to counter indices", func() {
+
+		It("returns the index of a listed CPU", func() {
+			cpus := CPUList{1, 42, 666}
+			for expected, cpu := range cpus {
+				idx, ok := cpus.Index(cpu)
+				Expect(ok).To(BeTrue(), "CPU %d", cpu)
+				Expect(idx).To(Equal(expected), "CPU %d", cpu)
+			}
+			Expect(cpus.Contains(42)).To(BeTrue())
+		})
+
+		It("reports unlisted CPUs", func() {
+			cpus := CPUList{1, 42, 666}
+			for _, cpu := range []uint{0, 2, 41, 667} {
+				_, ok := cpus.Index(cpu)
+				Expect(ok).To(BeFalse(), "CPU %d", cpu)
+				Expect(cpus.Contains(cpu)).To(BeFalse(), "CPU %d", cpu)
+			}
+			Expect(CPUList{}.Contains(0)).To(BeFalse())
+		})
+
+		It("maps CPU numbers to indices", func() {
+			Expect(CPUList{}.IndexMap()).To(BeEmpty())
+			cpus := CPUList{1, 42, 666}
+			indices := cpus.IndexMap()
+			Expect(indices).To(Equal(map[uint]int{1: 0, 42: 1, 666: 2}))
+			for cpu, idx := range indices {
+				expected, ok := cpus.Index(cpu)
+				Expect(ok).To(BeTrue())
+				Expect(idx).To(Equal(expected))
+			}
+		})
+
 	})
 
 	When("reading all IRQ counters", func() {
@@ -115,6 +215,59 @@ var _ = Describe("irksome", func() {
 					HaveField("Counters", HaveExactElements(uint64(6), uint64(7), uint64(8))))))
 		})
 
+		It("skips malformed lines", func() {
+			r := strings.NewReader(` CPU1 CPU42
+ 1: 2
+ ENEMIH: 1 2 zz
+ 3: 4abc 5
+ 6: 7 8 y
+ 9 10 11
+`)
+			irqs := safelyCollectIRQs(allCounters(r, nil))
+			Expect(irqs).To(HaveExactElements(
+				And(HaveField("Num", uint(6)),
+					HaveField("Counters", HaveExactElements(uint64(7), uint64(8))))))
+		})
+
+		It("copes with very long lines", func() {
+			const numCPUs = 8192
+			var text strings.Builder
+			text.WriteString("    ")
+			for cpu := range numCPUs {
+				fmt.Fprintf(&text, " %10s", fmt.Sprintf("CPU%d", cpu))
+			}
+			text.WriteString("\n  42:")
+			for cpu := range numCPUs {
+				fmt.Fprintf(&text, " %10d", cpu)
+			}
+			text.WriteString("  IR-PCI-MSI  0-edge  foo\n")
+			Expect(text.Len()).To(BeNumerically(">", 2*bufio.MaxScanTokenSize))
+
+			irqs := safelyCollectIRQs(allCounters(strings.NewReader(text.String()), nil))
+			Expect(irqs).To(HaveLen(1))
+			Expect(irqs[0].CPUs).To(HaveLen(numCPUs))
+			Expect(irqs[0].Counters).To(HaveLen(numCPUs))
+			Expect(irqs[0].Counters[numCPUs-1]).To(Equal(uint64(numCPUs - 1)))
+		})
+
+		It("clones IRQs", func() {
+			irq := IRQ{Num: 42, Counters: []uint64{1, 2}, CPUs: CPUList{0, 1}}
+			clone := irq.Clone()
+			Expect(clone).To(Equal(irq))
+			irq.Counters[0] = 666
+			Expect(clone.Counters).To(HaveExactElements(uint64(1), uint64(2)))
+		})
+
+		It("sums the counters of an IRQ", func() {
+			r := strings.NewReader(procInterruptsText)
+			totals := map[uint]uint64{}
+			for irq := range allCounters(r, nil) {
+				totals[irq.Num] = irq.Total()
+			}
+			Expect(totals).To(Equal(map[uint]uint64{1: 9, 5: 21}))
+			Expect(IRQ{}.Total()).To(BeZero())
+		})
+
 		It("stops the yield when told", func() {
 			r := strings.NewReader(procInterruptsText)
 			items := 0
@@ -142,6 +295,221 @@ var _ = Describe("irksome", func() {
 
 	})
 
+	When("reading IRQ counters from a different root", func() {
+
+		It("yields nothing for a non-existing root", func() {
+			Expect(AllCountersFrom("./testdata/non-existing")).To(BeEmpty())
+			Expect(CountersForFrom("./testdata/non-existing", []uint{42})).To(BeEmpty())
+		})
+
+		It("yields the correct IRQ information", func() {
+			irqs := safelyCollectIRQs(AllCountersFrom("./testdata/mixed"))
+			Expect(irqs).To(HaveEach(
+				HaveField("CPUs", HaveExactElements(uint(0), uint(1), uint(2), uint(3)))))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(0)),
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(8)),
+				And(HaveField("Num", uint(42)),
+					HaveField("Counters", HaveExactElements(
+						uint64(0), uint64(1234), uint64(0), uint64(56)))),
+				HaveField("Num", uint(43))))
+		})
+
+		It("yields only the wanted IRQs", func() {
+			irqs := safelyCollectIRQs(CountersForFrom("./testdata/mixed", []uint{1, 42, 666}))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(42))))
+		})
+
+	})
+
+	When("parsing tab-indented counters", func() {
+
+		const tabbedText = "\tCPU1\tCPU42\n\t1:\t2\t3\tx\n\tNMI:\t4\t5\tNon-maskable interrupts\n"
+
+		It("yields the correct IRQ information", func() {
+			Expect(safelyCollectIRQs(allCounters(strings.NewReader(tabbedText), nil))).To(HaveExactElements(
+				IRQ{Num: 1, Counters: []uint64{2, 3}, CPUs: CPUList{1, 42}}))
+			infos := []IRQInfo{}
+			for info := range allCountersWithInfo(strings.NewReader(tabbedText)) {
+				info.Counters = nil
+				infos = append(infos, info)
+			}
+			Expect(infos).To(HaveExactElements(And(
+				HaveField("Num", uint(1)),
+				HaveField("ChipName", "x"))))
+			Expect(safelyCollectNamedInterrupts(allNamedCounters(strings.NewReader(tabbedText)))).To(
+				HaveExactElements(And(
+					HaveField("Name", "NMI"),
+					HaveField("Counters", HaveExactElements(uint64(4), uint64(5))),
+					HaveField("Description", "Non-maskable interrupts"))))
+		})
+
+	})
+
+	When("reading only active counters", func() {
+
+		It("skips IRQs that never fired", func() {
+			irqs := safelyCollectIRQs(activeCounters(allCounters(strings.NewReader(` CPU0 CPU1
+ 1: 0 0 x
+ 2: 0 1 y
+ 3: 0 0 z
+ 4: 5 0 zz
+`), nil)))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(2)),
+				HaveField("Num", uint(4))))
+		})
+
+		It("stops the yield when told", func() {
+			items := 0
+			for range activeCounters(allCounters(strings.NewReader(procInterruptsText), nil)) {
+				items++
+				break
+			}
+			Expect(items).To(Equal(1))
+		})
+
+		It("reads real active counters", func() {
+			Expect(ActiveCounters()).To(HaveEach(
+				WithTransform(IRQ.Total, BeNumerically(">", 0))))
+		})
+
+	})
+
+	When("ranking IRQs by their total counts", func() {
+
+		It("returns the top IRQs", func() {
+			it := allCounters(strings.NewReader(` CPU0 CPU1
+ 1: 0 0 x
+ 2: 0 7 y
+ 3: 3 4 z
+ 4: 5 6 zz
+`), nil)
+			irqs := topCounters(it, 3)
+			Expect(irqs).To(HaveExactElements(
+				IRQ{Num: 4, CPUs: CPUList{0, 1}, Counters: []uint64{5, 6}},
+				IRQ{Num: 2, CPUs: CPUList{0, 1}, Counters: []uint64{0, 7}},
+				IRQ{Num: 3, CPUs: CPUList{0, 1}, Counters: []uint64{3, 4}}))
+		})
+
+		It("returns fewer IRQs when there aren't enough", func() {
+			Expect(topCounters(allCounters(strings.NewReader(procInterruptsText), nil), 42)).To(
+				HaveExactElements(
+					HaveField("Num", uint(5)),
+					HaveField("Num", uint(1))))
+			Expect(topCounters(allCounters(strings.NewReader(procInterruptsText), nil), 0)).To(BeNil())
+		})
+
+		It("ranks real IRQs", func() {
+			irqs := TopCounters(3)
+			Expect(len(irqs)).To(BeNumerically("<=", 3))
+			Expect(slices.IsSortedFunc(irqs, func(a, b IRQ) int {
+				return cmp.Compare(b.Total(), a.Total())
+			})).To(BeTrue())
+		})
+
+	})
+
+	When("reading counters for a single CPU", func() {
+
+		It("yields the counters for the CPU", func() {
+			counts := map[uint]uint64{}
+			for irqnum, count := range countersForCPU(
+				allCounters(strings.NewReader(procInterruptsText), nil), 42) {
+				counts[irqnum] = count
+			}
+			Expect(counts).To(Equal(map[uint]uint64{1: 3, 5: 7}))
+		})
+
+		It("yields nothing for an offline CPU", func() {
+			Expect(countersForCPU(
+				allCounters(strings.NewReader(procInterruptsText), nil), 2)).To(BeEmpty())
+		})
+
+		It("stops the yield when told", func() {
+			items := 0
+			for range countersForCPU(
+				allCounters(strings.NewReader(procInterruptsText), nil), 1) {
+				items++
+				break
+			}
+			Expect(items).To(Equal(1))
+		})
+
+		It("reads counters for a real CPU", func() {
+			cpus, ok := OnlineCPUs()
+			Expect(ok).To(BeTrue())
+			Expect(CountersForCPU(cpus[0])).NotTo(BeEmpty())
+		})
+
+	})
+
+	When("parsing IRQ counters from a reader", func() {
+
+		It("yields the correct IRQ information", func() {
+			irqs := safelyCollectIRQs(ParseCounters(strings.NewReader(procInterruptsText)))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(5))))
+
+			irqs = safelyCollectIRQs(ParseCountersFor(strings.NewReader(procInterruptsText), []uint{5}))
+			Expect(irqs).To(HaveExactElements(
+				And(HaveField("Num", uint(5)),
+					HaveField("Counters", HaveExactElements(uint64(6), uint64(7), uint64(8))))))
+		})
+
+	})
+
+	When("parsing IRQ counters from a file", func() {
+
+		It("yields the IRQs from plain and gzipped files", func() {
+			for _, path := range []string{
+				"./testdata/mixed/proc/interrupts",
+				"./testdata/snapshots/interrupts.gz",
+			} {
+				irqs := safelyCollectIRQs(ParseCountersFile(path))
+				Expect(irqs).To(HaveExactElements(
+					HaveField("Num", uint(0)),
+					HaveField("Num", uint(1)),
+					HaveField("Num", uint(8)),
+					HaveField("Num", uint(42)),
+					HaveField("Num", uint(43))), "file %s", path)
+				Expect(irqs[3].Counters).To(HaveExactElements(
+					uint64(0), uint64(1234), uint64(0), uint64(56)))
+			}
+		})
+
+		It("skips IRQs with mismatched counter columns", func() {
+			irqs := safelyCollectIRQs(ParseCountersFile("./testdata/hotplug/proc/interrupts"))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(0)),
+				HaveField("Num", uint(42)),
+				HaveField("Num", uint(44))))
+			Expect(irqs[1].Counters).To(HaveExactElements(uint64(0), uint64(1234), uint64(56)))
+		})
+
+		It("yields the IRQs from a file at an arbitrary path", func() {
+			path := GinkgoT().TempDir() + "/host123-interrupts.txt"
+			Expect(os.WriteFile(path, []byte(procInterruptsText), 0644)).To(Succeed())
+			Expect(safelyCollectIRQs(ParseCountersFile(path))).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(5))))
+		})
+
+		It("yields nothing for non-existing or non-gzipped files", func() {
+			Expect(ParseCountersFile("./testdata/non-existing")).To(BeEmpty())
+			Expect(ParseCountersFile("./testdata/non-existing.gz")).To(BeEmpty())
+			tmpdir := GinkgoT().TempDir()
+			path := tmpdir + "/interrupts.gz"
+			Expect(os.WriteFile(path, []byte(procInterruptsText), 0644)).To(Succeed())
+			Expect(ParseCountersFile(path)).To(BeEmpty())
+		})
+
+	})
+
 	When("wanting only counters for certain IRQs", func() {
 
 		It("yields the correct IRQ information", func() {
@@ -158,6 +526,51 @@ var _ = Describe("irksome", func() {
 				HaveField("Num", uint(666))))
 		})
 
+		It("stops after the highest requested IRQ", func() {
+			r := strings.NewReader(` CPU1 CPU42 CPU666
+ 1: 2 3 4 x
+ 42: 6 7 8 y
+ 5: 9 10 11 z
+`)
+			Expect(safelyCollectIRQs(allCounters(r, []uint{1, 5}))).To(HaveExactElements(
+				HaveField("Num", uint(1))))
+			r = strings.NewReader(` CPU1 CPU42 CPU666
+ 1: 2 3 4 x
+ 5: 6 7 8 y
+ 42: 9 10 11 z
+`)
+			Expect(safelyCollectIRQs(allCounters(r, []uint{1, 5}))).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(5))))
+			Expect(allCounters(strings.NewReader(procInterruptsText), []uint{})).To(BeEmpty())
+		})
+
+		It("falls back to a full scan for IRQs not in ascending order", func() {
+			r := strings.NewReader(` CPU1 CPU42 CPU666
+ 1: 2 3 4 x
+ 0: 6 7 8 y
+ 3: 9 10 11 z
+ 2: 12 13 14 zz
+`)
+			Expect(safelyCollectIRQs(allCounters(r, []uint{2, 3}))).To(HaveExactElements(
+				HaveField("Num", uint(3)),
+				HaveField("Num", uint(2))))
+		})
+
+		It("copes with unsorted IRQ numbers", func() {
+			Expect(sortedIRQNums(nil)).To(BeEmpty())
+			sorted := []uint{1, 42, 666}
+			Expect(&sortedIRQNums(sorted)[0]).To(BeIdenticalTo(&sorted[0]))
+			unsorted := []uint{666, 1, 42}
+			Expect(sortedIRQNums(unsorted)).To(HaveExactElements(uint(1), uint(42), uint(666)))
+			Expect(unsorted).To(HaveExactElements(uint(666), uint(1), uint(42)))
+
+			irqs := safelyCollectIRQs(ParseCountersFor(strings.NewReader(procInterruptsText), []uint{5, 1}))
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(5))))
+		})
+
 		It("produces only wanted IRQ information", func() {
 			allirqs := safelyCollectIRQs(AllCounters())
 			irqnums := []uint{}
@@ -0,0 +1,48 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/thediveo/cpus"
+)
+
+// cpuMask returns the CPU list for the passed hexadecimal CPU mask in the
+// format of “/proc/irq/#/smp_affinity”, such as “00000000,0000000f”. The mask
+// consists of comma-separated groups of 32 bits each, with the rightmost group
+// representing the lowest CPUs 0-31.
+func cpuMask(b []byte) (cpus.List, error) {
+	numGroups := bytes.Count(b, []byte{','}) + 1
+	set := make(cpus.Set, (numGroups+1)/2)
+	for group := 0; group < numGroups; group++ {
+		var hex []byte
+		if sep := bytes.LastIndexByte(b, ','); sep >= 0 {
+			hex, b = b[sep+1:], b[:sep]
+		} else {
+			hex = b
+		}
+		if len(hex) > 8 {
+			return nil, errors.New("mask group exceeds 32 bits")
+		}
+		bits, ok := parseHex(hex)
+		if !ok {
+			return nil, errors.New("expected hexadecimal mask group")
+		}
+		set[group/2] |= bits << ((group % 2) * 32)
+	}
+	return set.List(), nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"github.com/thediveo/cpus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("hex CPU masks", func() {
+
+	DescribeTable("decoding masks",
+		func(mask string, expected string) {
+			Expect(cpuMask([]byte(mask))).To(Equal(Successful(cpus.NewList([]byte(expected)))))
+		},
+		Entry("no CPUs", "00000000", ""),
+		Entry("single group", "f", "0-3"),
+		Entry("two groups", "00000000,0000000f", "0-3"),
+		Entry("upper group only", "00000001,00000000", "32"),
+		Entry("three groups", "ffffffff,ffffffff,00000001", "0,32-95"),
+		Entry("sparse", "80000000,00000005", "0,2,63"),
+	)
+
+	It("rejects malformed masks", func() {
+		for _, mask := range []string{"", "g", "f,", ",f", "1ffffffff", "f,,f"} {
+			Expect(cpuMask([]byte(mask))).Error().To(HaveOccurred(), "mask %q", mask)
+		}
+	})
+
+})
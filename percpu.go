@@ -0,0 +1,56 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/thediveo/faf"
+)
+
+const perCPUCountNode = "/per_cpu_count"
+
+// PerCPUCount returns the interrupt counters of the specified IRQ for all CPUs
+// in the system, online as well as offline, as read from
+// “/sys/kernel/irq/#/per_cpu_count”. It reports false if the IRQ doesn't
+// exist or its counters cannot be read.
+//
+// Please note that in contrast to [IRQ] Counters, the index into the returned
+// counters is the absolute CPU number, and not an index into a [CPUList] of
+// online CPUs.
+func PerCPUCount(irqnum uint) ([]uint64, bool) {
+	return perCPUCount("", irqnum)
+}
+
+// perCPUCount returns the interrupt counters of the specified IRQ for all
+// CPUs, with the pseudo files located beneath the specified root.
+func perCPUCount(root string, irqnum uint) ([]uint64, bool) {
+	contents, ok := faf.ReadFile(
+		root+syskernelirqPath+strconv.FormatUint(uint64(irqnum), 10)+perCPUCountNode, nil)
+	if !ok {
+		return nil, false
+	}
+	line, ok := lineOf(contents)
+	if !ok || len(line) == 0 {
+		return nil, false
+	}
+	counters, ok := appendUint64CSV(
+		make([]uint64, 0, bytes.Count(line, []byte{','})+1), line)
+	if !ok {
+		return nil, false
+	}
+	return counters, true
+}
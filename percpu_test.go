@@ -0,0 +1,61 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("per-CPU counts", func() {
+
+	It("reads the counters of all CPUs", func() {
+		counters, ok := perCPUCount("./testdata/mixed", 42)
+		Expect(ok).To(BeTrue())
+		Expect(counters).To(HaveExactElements(
+			uint64(0), uint64(1234), uint64(0), uint64(56),
+			uint64(0), uint64(0), uint64(0), uint64(0)))
+	})
+
+	It("reports non-existing IRQs", func() {
+		_, ok := perCPUCount("./testdata/mixed", 666)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects malformed counters", func() {
+		root := GinkgoT().TempDir()
+		irqdir := filepath.Join(root, syskernelirqPath, "1")
+		Expect(os.MkdirAll(irqdir, 0o755)).To(Succeed())
+		for _, text := range []string{"", "\n", "1,2", "1,,2\n", "1,2,\n", "1,x\n"} {
+			Expect(os.WriteFile(filepath.Join(irqdir, perCPUCountNode), []byte(text), 0o644)).
+				To(Succeed())
+			_, ok := perCPUCount(root, 1)
+			Expect(ok).To(BeFalse(), "contents %q", text)
+		}
+	})
+
+	It("reads real per-CPU counts", func() {
+		for irq := range AllCounters() {
+			counters, ok := PerCPUCount(irq.Num)
+			Expect(ok).To(BeTrue())
+			Expect(len(counters)).To(BeNumerically(">=", len(irq.CPUs)))
+			break
+		}
+	})
+
+})
@@ -0,0 +1,63 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+)
+
+// ErrUnsupportedPlatform indicates that IRQ information isn't available on
+// this platform, as it either isn't Linux or lacks “/proc/interrupts”. This
+// allows callers to tell an unsupported platform apart from a system without
+// any IRQs.
+var ErrUnsupportedPlatform = errors.New("unsupported platform: Linux IRQ information not available")
+
+// supportedPlatform returns [ErrUnsupportedPlatform] if not running on Linux,
+// or if “/proc/interrupts” beneath the specified root doesn't exist; otherwise
+// it returns nil.
+func supportedPlatform(root string) error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupportedPlatform
+	}
+	if _, err := os.Stat(root + procInterruptsPath); errors.Is(err, fs.ErrNotExist) {
+		return ErrUnsupportedPlatform
+	}
+	return nil
+}
+
+// openProcInterrupts opens “/proc/interrupts” beneath the specified root. If
+// it cannot be opened, openProcInterrupts returns [ErrUnsupportedPlatform] if
+// not running on Linux or “/proc/interrupts” doesn't exist, and otherwise the
+// original error.
+func openProcInterrupts(root string) (*os.File, error) {
+	return openProcFile(root, procInterruptsPath)
+}
+
+// openProcFile opens the specified procfs pseudo file, such as
+// “/proc/softirqs”, beneath the specified root, mapping errors the same way
+// as [openProcInterrupts] does.
+func openProcFile(root string, name string) (*os.File, error) {
+	f, err := os.Open(root + name)
+	if err != nil {
+		if perr := supportedPlatform(root); perr != nil {
+			return nil, perr
+		}
+		return nil, err
+	}
+	return f, nil
+}
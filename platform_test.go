@@ -0,0 +1,64 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("platform support", func() {
+
+	It("detects a missing /proc/interrupts", func() {
+		Expect(supportedPlatform("./testdata/non-existing")).To(MatchError(ErrUnsupportedPlatform))
+		Expect(supportedPlatform("./testdata/mixed")).To(Succeed())
+		Expect(supportedPlatform("")).To(Succeed())
+	})
+
+	It("opens /proc/interrupts", func() {
+		f, err := openProcInterrupts("./testdata/mixed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		_, err = openProcInterrupts("./testdata/non-existing")
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+	})
+
+	It("opens other procfs files", func() {
+		f, err := openProcFile("./testdata/mixed", procSoftIRQsPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		_, err = openProcFile("./testdata/non-existing", procSoftIRQsPath)
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+		_, err = openProcFile("./testdata/arm64", procSoftIRQsPath)
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		Expect(err).NotTo(MatchError(ErrUnsupportedPlatform))
+	})
+
+	It("reports an unsupported platform when collecting", func() {
+		_, err := collectIntoFrom("./testdata/non-existing", map[uint][]uint64{})
+		Expect(err).To(MatchError(ErrUnsupportedPlatform))
+	})
+
+	It("doesn't report an unsupported platform when watching on Linux", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(Watch(ctx, time.Millisecond, func([]IRQ) {})).To(MatchError(context.Canceled))
+	})
+
+})
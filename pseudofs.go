@@ -0,0 +1,104 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"io/fs"
+	"iter"
+	"os"
+	"strings"
+
+	"github.com/thediveo/faf"
+)
+
+// pseudoFS abstracts access to the IRQ-related pseudo files, so that the IRQ
+// details can be gathered using the optimized faf functions in production, as
+// well as through an [fs.FS].
+type pseudoFS struct {
+	// subdirs returns an iterator over the names of the subdirectories of
+	// the specified directory.
+	subdirs func(dir string) iter.Seq[[]byte]
+	// readFile reads the contents of the specified file, reusing the passed
+	// buffer where possible, and reports false if the file cannot be read.
+	readFile func(name string, buffer []byte) ([]byte, bool)
+	// isDir reports whether the specified directory exists.
+	isDir func(name string) bool
+}
+
+// fafPseudoFS accesses the pseudo files using the optimized faf functions.
+var fafPseudoFS = pseudoFS{
+	subdirs: func(dir string) iter.Seq[[]byte] {
+		return func(yield func([]byte) bool) {
+			for entry := range faf.ReadDir(dir) {
+				if !entry.IsDir() {
+					continue
+				}
+				if !yield(entry.Name) {
+					return
+				}
+			}
+		}
+	},
+	readFile: faf.ReadFile,
+	isDir: func(name string) bool {
+		info, err := os.Stat(name)
+		return err == nil && info.IsDir()
+	},
+}
+
+// fsPseudoFS returns a pseudoFS accessing the pseudo files through the
+// specified [fs.FS]. As fs.FS paths must not be rooted, any leading slash gets
+// removed.
+func fsPseudoFS(fsys fs.FS) pseudoFS {
+	return pseudoFS{
+		subdirs: func(dir string) iter.Seq[[]byte] {
+			return func(yield func([]byte) bool) {
+				entries, err := fs.ReadDir(fsys, fsPath(dir))
+				if err != nil {
+					return
+				}
+				for _, entry := range entries {
+					if !entry.IsDir() {
+						continue
+					}
+					if !yield([]byte(entry.Name())) {
+						return
+					}
+				}
+			}
+		},
+		readFile: func(name string, buffer []byte) ([]byte, bool) {
+			contents, err := fs.ReadFile(fsys, fsPath(name))
+			if err != nil {
+				return buffer, false
+			}
+			return append(buffer[:0], contents...), true
+		},
+		isDir: func(name string) bool {
+			info, err := fs.Stat(fsys, fsPath(name))
+			return err == nil && info.IsDir()
+		},
+	}
+}
+
+// fsPath returns the specified path in the unrooted form required by [fs.FS],
+// also without any trailing slash.
+func fsPath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "."
+	}
+	return path
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing/fstest"
+
+	"github.com/thediveo/cpus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("pseudo file system access", func() {
+
+	It("returns unrooted fs.FS paths", func() {
+		Expect(fsPath("")).To(Equal("."))
+		Expect(fsPath("/")).To(Equal("."))
+		Expect(fsPath("/sys/kernel/irq/")).To(Equal("sys/kernel/irq"))
+		Expect(fsPath("/proc/irq/42/node")).To(Equal("proc/irq/42/node"))
+	})
+
+	It("reads the same details through an fs.FS", func() {
+		Expect(AllIRQDetailsFS(os.DirFS("./testdata/mixed"))).To(ConsistOf(
+			slices.Collect(allIRQDetails(context.Background(), "./testdata/mixed"))))
+	})
+
+	It("reads details from a map-based file system", func() {
+		fsys := fstest.MapFS{
+			"sys/kernel/irq/1/actions":           {Data: []byte("i8042\n")},
+			"sys/kernel/irq/1/type":              {Data: []byte("edge\n")},
+			"sys/kernel/irq/2/actions":           {Data: []byte("foo\n")},
+			"sys/kernel/irq/README":              {Data: []byte("not an IRQ\n")},
+			"proc/irq/1/effective_affinity_list": {Data: []byte("3\n")},
+			"proc/irq/1/smp_affinity":            {Data: []byte("0000000f\n")},
+			"proc/irq/2/effective_affinity_list": {Data: []byte("\n")},
+			"proc/irq/default_smp_affinity":      {Data: []byte("f\n")},
+		}
+		Expect(AllIRQDetailsFS(fsys)).To(ConsistOf(
+			IRQDetails{
+				Num:                  1,
+				Actions:              "i8042",
+				Affinities:           Successful(cpus.NewList([]byte("3"))),
+				ConfiguredAffinities: Successful(cpus.NewList([]byte("0-3"))),
+				TriggerType:          "edge",
+				Node:                 -1,
+			},
+			IRQDetails{
+				Num:     2,
+				Actions: "foo",
+				Node:    -1,
+			}))
+		Expect(AllIRQDetailsFS(fstest.MapFS{})).To(BeEmpty())
+	})
+
+	It("stops the yield when told", func() {
+		items := 0
+		for range AllIRQDetailsFS(os.DirFS("./testdata/mixed")) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+})
@@ -0,0 +1,132 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// QueueInfo describes an IRQ action of a multiqueue device, such as a network
+// card with its action “eth0-TxRx-1” being device “eth0”, kind “TxRx”, and
+// queue index 1.
+type QueueInfo struct {
+	Device     string // device name, such as "eth0".
+	Kind       string // kind of queue, such as "TxRx", "rx", or "tx".
+	QueueIndex int    // index of the queue.
+}
+
+// ParseAction parses the specified IRQ action in the common
+// “<dev>-<kind>-<n>” format of multiqueue devices, returning the device name,
+// the queue kind and index. The device name might contain dashes itself, as
+// only the last two dash-separated elements are taken as the kind and queue
+// index. ParseAction reports false if the action doesn't follow this format.
+func ParseAction(action string) (device string, kind string, queue int, ok bool) {
+	rest, index, found := cutLast(action, "-")
+	// Only accept plain decimal digits, without any sign.
+	if !found || !isDecimal(index) {
+		return "", "", 0, false
+	}
+	queue, err := strconv.Atoi(index)
+	if err != nil {
+		return "", "", 0, false
+	}
+	device, kind, found = cutLast(rest, "-")
+	if !found || device == "" || kind == "" {
+		return "", "", 0, false
+	}
+	return device, kind, queue, true
+}
+
+// Queues returns the queue information for the actions of this IRQ that follow
+// the “<dev>-<kind>-<n>” format of multiqueue devices, please see
+// [ParseAction] for details. Actions not in this format are omitted. If there
+// are no such actions, Queues returns nil.
+func (d IRQDetails) Queues() []QueueInfo {
+	var queues []QueueInfo
+	for action := range d.EachAction {
+		device, kind, queue, ok := ParseAction(strings.TrimSpace(action))
+		if !ok {
+			continue
+		}
+		queues = append(queues, QueueInfo{
+			Device:     device,
+			Kind:       kind,
+			QueueIndex: queue,
+		})
+	}
+	return queues
+}
+
+// Drivers returns the de-duplicated base device or driver identifiers of the
+// actions of this IRQ, in order of their first appearance. This is only a
+// heuristic, as actions are names given by drivers when requesting IRQs and not
+// necessarily device or driver names. Drivers strips multiqueue suffixes, such
+// as in “eth0-TxRx-1” (please see [ParseAction]), as well as plain queue
+// indices, such as in “eth0-3” and “nvme0q1”. Other actions are taken as they
+// are. If there are no actions, Drivers returns nil.
+func (d IRQDetails) Drivers() []string {
+	var drivers []string
+	for action := range d.EachAction {
+		action = strings.TrimSpace(action)
+		if action == "" {
+			continue
+		}
+		driver := baseDriver(action)
+		if slices.Contains(drivers, driver) {
+			continue
+		}
+		drivers = append(drivers, driver)
+	}
+	return drivers
+}
+
+// baseDriver returns the specified action with any multiqueue suffix or queue
+// index stripped.
+func baseDriver(action string) string {
+	if device, _, _, ok := ParseAction(action); ok {
+		return device
+	}
+	if device, index, found := cutLast(action, "-"); found && device != "" && isDecimal(index) {
+		return device
+	}
+	if device, index, found := cutLast(action, "q"); found && device != "" && isDecimal(index) {
+		return device
+	}
+	return action
+}
+
+// isDecimal reports whether s is a non-empty string of only decimal digits.
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ch := range []byte(s) {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// cutLast slices s around the last instance of sep, returning the text before
+// and after sep. If sep doesn't appear in s, cutLast returns s, "", false.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if idx := strings.LastIndex(s, sep); idx >= 0 {
+		return s[:idx], s[idx+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("multiqueue actions", func() {
+
+	DescribeTable("parsing actions",
+		func(action string, device string, kind string, queue int, ok bool) {
+			d, k, q, o := ParseAction(action)
+			Expect(o).To(Equal(ok))
+			Expect(d).To(Equal(device))
+			Expect(k).To(Equal(kind))
+			Expect(q).To(Equal(queue))
+		},
+		Entry("TxRx queue", "eth0-TxRx-0", "eth0", "TxRx", 0, true),
+		Entry("rx queue", "enp3s0-rx-12", "enp3s0", "rx", 12, true),
+		Entry("device name with dashes", "i40e-eth0-TxRx-3", "i40e-eth0", "TxRx", 3, true),
+		Entry("empty action", "", "", "", 0, false),
+		Entry("single-queue action", "i8042", "", "", 0, false),
+		Entry("missing queue index", "eth0-TxRx", "", "", 0, false),
+		Entry("empty queue index", "eth0-TxRx-", "", "", 0, false),
+		Entry("signed queue index", "eth0-TxRx-+1", "", "", 0, false),
+		Entry("non-numeric queue index", "eth0-TxRx-1a", "", "", 0, false),
+		Entry("missing device", "-TxRx-1", "", "", 0, false),
+		Entry("missing kind", "eth0--1", "", "", 0, false),
+		Entry("no dashes", "nvme0q1", "", "", 0, false),
+		Entry("overflowing queue index", "eth0-TxRx-99999999999999999999999", "", "", 0, false),
+	)
+
+	It("returns the queues of an IRQ", func() {
+		Expect(IRQDetails{}.Queues()).To(BeNil())
+		Expect(IRQDetails{Actions: "i8042"}.Queues()).To(BeNil())
+		Expect(IRQDetails{Actions: "eth0-TxRx-0,foo,eth1-rx-7"}.Queues()).To(HaveExactElements(
+			QueueInfo{Device: "eth0", Kind: "TxRx", QueueIndex: 0},
+			QueueInfo{Device: "eth1", Kind: "rx", QueueIndex: 7}))
+		Expect(IRQDetails{Actions: "eth0-TxRx-0, eth0-TxRx-1"}.Queues()).To(HaveExactElements(
+			QueueInfo{Device: "eth0", Kind: "TxRx", QueueIndex: 0},
+			QueueInfo{Device: "eth0", Kind: "TxRx", QueueIndex: 1}))
+	})
+
+	DescribeTable("guessing base drivers",
+		func(action string, driver string) {
+			Expect(baseDriver(action)).To(Equal(driver))
+		},
+		Entry("TxRx queue", "eth0-TxRx-1", "eth0"),
+		Entry("rx queue", "enp3s0-rx-12", "enp3s0"),
+		Entry("plain queue index", "eth0-3", "eth0"),
+		Entry("NVMe queue", "nvme0q1", "nvme0"),
+		Entry("no queue", "i8042", "i8042"),
+		Entry("dashed name", "xhci-hcd", "xhci-hcd"),
+		Entry("missing device", "-1", "-1"),
+		Entry("missing NVMe index", "nvme0q", "nvme0q"),
+	)
+
+	It("returns the de-duplicated drivers of an IRQ", func() {
+		Expect(IRQDetails{}.Drivers()).To(BeNil())
+		Expect(IRQDetails{Actions: ","}.Drivers()).To(BeNil())
+		Expect(IRQDetails{Actions: "eth0-TxRx-0, eth0-TxRx-1"}.Drivers()).To(HaveExactElements("eth0"))
+		Expect(IRQDetails{Actions: "eth0-TxRx-0,i8042,eth1-rx-7,eth0-tx-1"}.Drivers()).To(
+			HaveExactElements("eth0", "i8042", "eth1"))
+	})
+
+})
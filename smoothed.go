@@ -0,0 +1,136 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"slices"
+)
+
+// SmoothedCollector repeatedly collects Snapshots of the per-CPU counters of
+// all (non-architecture-specific) IRQs, similar to [Collector], but smooths
+// over CPUs briefly going offline and thus their counter columns temporarily
+// vanishing from “/proc/interrupts”. For this, a SmoothedCollector retains the
+// most recent Snapshots and fills in the counters of CPUs missing from the
+// latest Snapshot with their latest known counters from the retained
+// Snapshots.
+//
+// A SmoothedCollector thus trades memory for stability: it keeps copies of the
+// retained Snapshots, so its memory consumption grows linearly with the number
+// of retained Snapshots. Please note that the counters of a CPU that went
+// offline permanently continue to be reported until all retained Snapshots
+// with this CPU have been superseded.
+//
+// A SmoothedCollector is not safe for concurrent use: Collect must not be
+// called from multiple goroutines at the same time.
+type SmoothedCollector struct {
+	collector Collector
+	depth     int        // maximum number of Snapshots to retain.
+	history   []Snapshot // retained Snapshots, from oldest to latest.
+}
+
+// NewSmoothedCollector returns a new SmoothedCollector retaining the specified
+// number of most recent Snapshots, including the latest Snapshot. A depth of
+// less than one is taken as one, that is, no smoothing at all.
+func NewSmoothedCollector(depth int) *SmoothedCollector {
+	return &SmoothedCollector{
+		depth: max(depth, 1),
+	}
+}
+
+// Collect collects a new Snapshot and returns the smoothed Snapshot, which
+// contains the IRQs from the latest Snapshot. The smoothed Snapshot covers the
+// CPUs from all retained Snapshots, where the counters of CPUs missing in the
+// latest Snapshot are taken from the most recent retained Snapshot that has
+// them. The returned Snapshot owns its counters and thus can be safely
+// retained.
+//
+// Collect returns an error if “/proc/interrupts” cannot be opened, in
+// particular [ErrUnsupportedPlatform] when not running on Linux.
+func (s *SmoothedCollector) Collect() (Snapshot, error) {
+	snap, err := s.collector.Collect()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(s.history) >= s.depth {
+		s.history = slices.Delete(s.history, 0, len(s.history)-s.depth+1)
+	}
+	retained := snapshotOf(slices.Values(snap.IRQs))
+	retained.Time = snap.Time
+	s.history = append(s.history, retained)
+	return smoothed(s.history), nil
+}
+
+// smoothed returns the smoothed Snapshot based on the specified Snapshots,
+// ordered from oldest to latest.
+func smoothed(history []Snapshot) Snapshot {
+	latest := history[len(history)-1]
+	if len(history) == 1 {
+		snap := snapshotOf(slices.Values(latest.IRQs))
+		snap.Time = latest.Time
+		return snap
+	}
+
+	var cpus CPUList
+	for _, snap := range history {
+		cpus = append(cpus, snap.CPUs...)
+	}
+	slices.Sort(cpus)
+	cpus = slices.Compact(cpus)
+
+	// Index the IRQs of the older Snapshots by their IRQ numbers, so we can
+	// quickly look up the counters of CPUs missing in the latest Snapshot.
+	irqIndices := make([]map[uint]int, len(history)-1)
+	for idx, snap := range history[:len(history)-1] {
+		indices := make(map[uint]int, len(snap.IRQs))
+		for irqIdx, irq := range snap.IRQs {
+			indices[irq.Num] = irqIdx
+		}
+		irqIndices[idx] = indices
+	}
+
+	smoothedSnap := Snapshot{
+		Time: latest.Time,
+		CPUs: cpus,
+		IRQs: make([]IRQ, 0, len(latest.IRQs)),
+	}
+	for _, irq := range latest.IRQs {
+		counters := make([]uint64, len(cpus))
+		for cpuIdx, cpu := range cpus {
+			if idx, ok := latest.CPUs.Index(cpu); ok {
+				counters[cpuIdx] = irq.Counters[idx]
+				continue
+			}
+			for histIdx := len(history) - 2; histIdx >= 0; histIdx-- {
+				snap := history[histIdx]
+				idx, ok := snap.CPUs.Index(cpu)
+				if !ok {
+					continue
+				}
+				irqIdx, ok := irqIndices[histIdx][irq.Num]
+				if !ok {
+					continue
+				}
+				counters[cpuIdx] = snap.IRQs[irqIdx].Counters[idx]
+				break
+			}
+		}
+		smoothedSnap.IRQs = append(smoothedSnap.IRQs, IRQ{
+			Num:      irq.Num,
+			CPUs:     cpus,
+			Counters: counters,
+		})
+	}
+	return smoothedSnap
+}
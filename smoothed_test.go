@@ -0,0 +1,89 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("smoothed collector", func() {
+
+	var root string
+
+	writeInterrupts := func(text string) {
+		Expect(os.WriteFile(filepath.Join(root, procInterruptsPath), []byte(text), 0o644)).
+			To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "proc"), 0o755)).To(Succeed())
+	})
+
+	It("fills in counters of briefly offline CPUs", func() {
+		s := NewSmoothedCollector(2)
+		s.collector.root = root
+
+		writeInterrupts(" CPU0 CPU1 CPU2\n 1: 10 20 30 x\n 2: 1 2 3 y\n")
+		snap := Successful(s.Collect())
+		Expect(snap.CPUs).To(HaveExactElements(uint(0), uint(1), uint(2)))
+		Expect(snap.IRQs).To(HaveExactElements(
+			IRQ{Num: 1, CPUs: CPUList{0, 1, 2}, Counters: []uint64{10, 20, 30}},
+			IRQ{Num: 2, CPUs: CPUList{0, 1, 2}, Counters: []uint64{1, 2, 3}}))
+
+		writeInterrupts(" CPU0 CPU2\n 1: 11 31 x\n 3: 5 6 z\n")
+		snap = Successful(s.Collect())
+		Expect(snap.CPUs).To(HaveExactElements(uint(0), uint(1), uint(2)))
+		Expect(snap.IRQs).To(HaveExactElements(
+			IRQ{Num: 1, CPUs: CPUList{0, 1, 2}, Counters: []uint64{11, 20, 31}},
+			IRQ{Num: 3, CPUs: CPUList{0, 1, 2}, Counters: []uint64{5, 0, 6}}))
+
+		writeInterrupts(" CPU0 CPU2\n 1: 12 32 x\n")
+		snap = Successful(s.Collect())
+		Expect(snap.CPUs).To(HaveExactElements(uint(0), uint(2)))
+		Expect(snap.IRQs).To(HaveExactElements(
+			IRQ{Num: 1, CPUs: CPUList{0, 2}, Counters: []uint64{12, 32}}))
+	})
+
+	It("doesn't smooth with a depth of one", func() {
+		s := NewSmoothedCollector(0)
+		s.collector.root = root
+		writeInterrupts(" CPU0 CPU1\n 1: 10 20 x\n")
+		Successful(s.Collect())
+		writeInterrupts(" CPU1\n 1: 21 x\n")
+		snap := Successful(s.Collect())
+		Expect(snap.IRQs).To(HaveExactElements(
+			IRQ{Num: 1, CPUs: CPUList{1}, Counters: []uint64{21}}))
+	})
+
+	It("reports errors", func() {
+		s := NewSmoothedCollector(2)
+		s.collector.root = root
+		_, err := s.Collect()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("collects from the system", func() {
+		s := NewSmoothedCollector(3)
+		Expect(Successful(s.Collect()).IRQs).NotTo(BeEmpty())
+		Expect(Successful(s.Collect()).IRQs).NotTo(BeEmpty())
+	})
+
+})
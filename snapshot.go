@@ -0,0 +1,218 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+)
+
+// Snapshot is a permanent copy of the per-CPU counters of all
+// (non-architecture-specific) IRQs taken in a single pass over
+// “/proc/interrupts”. In contrast to the IRQs produced by the counter
+// iterators, the IRQs in a Snapshot own their Counters and thus can be
+// safely retained.
+type Snapshot struct {
+	Time time.Time // when the Snapshot was taken.
+	CPUs CPUList   // list of the number of the CPUs that were online.
+	IRQs []IRQ     // IRQs with their per-CPU counters, in ascending IRQ order.
+}
+
+// IRQRate holds the per-CPU interrupt rates of a particular IRQ in interrupts
+// per second.
+type IRQRate struct {
+	Num   uint      // IRQ number
+	CPUs  CPUList   // list of the number of the CPUs the rates are for.
+	Rates []float64 // per-CPU rates in interrupts per second.
+}
+
+// TakeSnapshot returns a new Snapshot of the per-CPU counters of all
+// (non-architecture-specific) IRQs.
+func TakeSnapshot() Snapshot {
+	now := time.Now()
+	snap := snapshotOf(AllCounters())
+	snap.Time = now
+	return snap
+}
+
+// snapshotOf returns a new Snapshot of the IRQs produced by the specified
+// iterator, cloning the transient per-CPU counters.
+func snapshotOf(it iter.Seq[IRQ]) Snapshot {
+	snap := Snapshot{}
+	for irq := range it {
+		snap.CPUs = irq.CPUs
+		snap.IRQs = append(snap.IRQs, irq.Clone())
+	}
+	return snap
+}
+
+// DeltaFiles returns the per-IRQ, per-CPU differences in counters between two
+// files in “/proc/interrupts” format captured before and after, such as at the
+// start and end of a test run. This is the offline counterpart to
+// [Snapshot.Delta], aligning the counters by IRQ and CPU numbers in the same
+// way and accepting the same options. As with [ParseCountersFile], files with
+// names ending in “.gz” are transparently decompressed. DeltaFiles returns an
+// error if either file cannot be read or doesn't contain any counters.
+func DeltaFiles(before, after string, opts ...DeltaOption) ([]IRQ, error) {
+	old, err := snapshotFile(before)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := snapshotFile(after)
+	if err != nil {
+		return nil, err
+	}
+	return old.Delta(newer, opts...), nil
+}
+
+// snapshotFile returns a Snapshot of the per-CPU counters of all IRQs read
+// from the specified file in “/proc/interrupts” format.
+func snapshotFile(path string) (Snapshot, error) {
+	r, err := openCountersFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer r.Close()
+	snap := snapshotOf(allCounters(r, nil))
+	if len(snap.CPUs) == 0 {
+		return Snapshot{}, fmt.Errorf("no IRQ counters in %s", path)
+	}
+	return snap, nil
+}
+
+// CPUsChanged reports whether the CPUs online differ between this (old)
+// Snapshot and a newer Snapshot, such as when CPUs have been hot(un)plugged in
+// between taking the Snapshots.
+func (old Snapshot) CPUsChanged(newer Snapshot) bool {
+	return !slices.Equal(old.CPUs, newer.CPUs)
+}
+
+// Delta returns the per-IRQ, per-CPU differences in counters between this
+// (old) Snapshot and a newer Snapshot. IRQs are matched by their IRQ numbers.
+// IRQs that have vanished in the new Snapshot are dropped, while IRQs that
+// newly appeared are treated as deltas from zero. The returned IRQs own their
+// Counters.
+//
+// The per-CPU counters are matched by their CPU numbers, not their positions,
+// so Delta correctly handles CPUs having gone offline or online in between
+// the two Snapshots (please see also [Snapshot.CPUsChanged]). The returned IRQs
+// always have the CPUs of the newer Snapshot: counters of CPUs that have gone
+// offline are dropped, while counters of CPUs that newly came online are
+// treated as deltas from zero.
+//
+// By default, Delta clamps the difference to zero when a newer counter is
+// smaller than its old counter, such as when an IRQ has been freed and
+// reallocated in between. Pass [WithCounterWrap] to instead treat smaller
+// newer counters as having wrapped around.
+func (old Snapshot) Delta(newer Snapshot, opts ...DeltaOption) []IRQ {
+	var options deltaOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	oldCounters := make(map[uint][]uint64, len(old.IRQs))
+	for _, irq := range old.IRQs {
+		oldCounters[irq.Num] = irq.Counters
+	}
+	// Map the counter positions of the newer CPUs to the positions of the
+	// same CPUs in the old Snapshot, if present at all.
+	oldIndices := make([]int, len(newer.CPUs))
+	for idx, cpu := range newer.CPUs {
+		oldIdx, ok := old.CPUs.Index(cpu)
+		if !ok {
+			oldIdx = -1
+		}
+		oldIndices[idx] = oldIdx
+	}
+	deltas := make([]IRQ, 0, len(newer.IRQs))
+	for _, irq := range newer.IRQs {
+		delta := IRQ{
+			Num:      irq.Num,
+			CPUs:     irq.CPUs,
+			Counters: slices.Clone(irq.Counters),
+		}
+		if counters, ok := oldCounters[irq.Num]; ok {
+			for idx := range min(len(oldIndices), len(delta.Counters)) {
+				if oldIdx := oldIndices[idx]; oldIdx >= 0 && oldIdx < len(counters) {
+					delta.Counters[idx] = options.sub(delta.Counters[idx], counters[oldIdx])
+				}
+			}
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+// DeltaOption configures how [Snapshot.Delta] computes counter differences.
+type DeltaOption func(*deltaOptions)
+
+type deltaOptions struct {
+	wrap    bool
+	modulus uint64
+}
+
+// WithCounterWrap configures [Snapshot.Delta] to treat a newer counter being
+// smaller than its old counter as a counter wraparound at the specified
+// modulus, such as 1<<32 for the kernel's 32-bit per-CPU interrupt counters. A
+// modulus of zero stands for 1<<64. Old counters not below a non-zero modulus
+// cannot have wrapped and thus still get clamped to zero.
+func WithCounterWrap(modulus uint64) DeltaOption {
+	return func(o *deltaOptions) {
+		o.wrap = true
+		o.modulus = modulus
+	}
+}
+
+// sub returns the difference between the newer and old counter values,
+// either clamped to zero or wrapped around when the newer value is smaller.
+func (o deltaOptions) sub(newer, old uint64) uint64 {
+	if newer >= old {
+		return newer - old
+	}
+	if !o.wrap || (o.modulus != 0 && old >= o.modulus) {
+		return 0
+	}
+	// Unsigned arithmetic is modulo 1<<64, so this also works for a zero
+	// modulus.
+	return o.modulus - old + newer
+}
+
+// Rate returns the per-IRQ, per-CPU interrupt rates in interrupts per second
+// between this (old) Snapshot and a newer Snapshot, based on their
+// [Snapshot.Delta] and the time elapsed between taking both Snapshots. Rate
+// returns an error if the elapsed time isn't positive, such as when the
+// Snapshots have been passed in the wrong order, or lack their times.
+func (old Snapshot) Rate(newer Snapshot) ([]IRQRate, error) {
+	elapsed := newer.Time.Sub(old.Time).Seconds()
+	if elapsed <= 0 || old.Time.IsZero() {
+		return nil, errors.New("elapsed time between snapshots must be positive")
+	}
+	deltas := old.Delta(newer)
+	rates := make([]IRQRate, 0, len(deltas))
+	for _, delta := range deltas {
+		rate := IRQRate{
+			Num:   delta.Num,
+			CPUs:  delta.CPUs,
+			Rates: make([]float64, len(delta.Counters)),
+		}
+		for idx, count := range delta.Counters {
+			rate.Rates[idx] = float64(count) / elapsed
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("snapshots", func() {
+
+	It("takes a permanent snapshot", func() {
+		snap := snapshotOf(allCounters(strings.NewReader(procInterruptsText), nil))
+		Expect(snap.CPUs).To(HaveExactElements(uint(1), uint(42), uint(666)))
+		Expect(snap.IRQs).To(HaveExactElements(
+			And(HaveField("Num", uint(1)),
+				HaveField("Counters", HaveExactElements(uint64(2), uint64(3), uint64(4)))),
+			And(HaveField("Num", uint(5)),
+				HaveField("Counters", HaveExactElements(uint64(6), uint64(7), uint64(8))))))
+	})
+
+	It("takes an empty snapshot when there are no IRQs", func() {
+		snap := snapshotOf(allCounters(strings.NewReader(""), nil))
+		Expect(snap.CPUs).To(BeEmpty())
+		Expect(snap.IRQs).To(BeEmpty())
+	})
+
+	It("computes deltas", func() {
+		old := snapshotOf(allCounters(strings.NewReader(procInterruptsText), nil))
+		newer := snapshotOf(allCounters(strings.NewReader(` CPU1 CPU42 CPU666
+ 1: 12 3 5 x
+ 7: 1 2 3 z
+`), nil))
+		Expect(old.Delta(newer)).To(HaveExactElements(
+			And(HaveField("Num", uint(1)),
+				HaveField("CPUs", HaveExactElements(uint(1), uint(42), uint(666))),
+				HaveField("Counters", HaveExactElements(uint64(10), uint64(0), uint64(1)))),
+			And(HaveField("Num", uint(7)),
+				HaveField("Counters", HaveExactElements(uint64(1), uint64(2), uint64(3))))))
+		Expect(newer.IRQs[0].Counters).To(HaveExactElements(uint64(12), uint64(3), uint64(5)))
+	})
+
+	It("detects changed CPUs", func() {
+		old := snapshotOf(allCounters(strings.NewReader(procInterruptsText), nil))
+		Expect(old.CPUsChanged(old)).To(BeFalse())
+		newer := snapshotOf(allCounters(strings.NewReader(` CPU1 CPU666
+ 1: 12 5 x
+`), nil))
+		Expect(old.CPUsChanged(newer)).To(BeTrue())
+		Expect(newer.CPUsChanged(old)).To(BeTrue())
+	})
+
+	It("computes deltas aligned by CPU numbers", func() {
+		old := snapshotOf(allCounters(strings.NewReader(procInterruptsText), nil))
+		newer := snapshotOf(allCounters(strings.NewReader(` CPU0 CPU1 CPU666
+ 1: 1 12 5 x
+ 5: 2 6 9 y
+`), nil))
+		Expect(old.Delta(newer)).To(HaveExactElements(
+			And(HaveField("Num", uint(1)),
+				HaveField("CPUs", HaveExactElements(uint(0), uint(1), uint(666))),
+				HaveField("Counters", HaveExactElements(uint64(1), uint64(10), uint64(1)))),
+			And(HaveField("Num", uint(5)),
+				HaveField("Counters", HaveExactElements(uint64(2), uint64(0), uint64(1))))))
+	})
+
+	It("clamps or wraps smaller newer counters", func() {
+		old := snapshotOf(allCounters(strings.NewReader(` CPU0 CPU1
+ 1: 4294967290 42 x
+`), nil))
+		newer := snapshotOf(allCounters(strings.NewReader(` CPU0 CPU1
+ 1: 5 1 x
+`), nil))
+		Expect(old.Delta(newer)).To(HaveExactElements(
+			HaveField("Counters", HaveExactElements(uint64(0), uint64(0)))))
+		Expect(old.Delta(newer, WithCounterWrap(1<<32))).To(HaveExactElements(
+			HaveField("Counters", HaveExactElements(uint64(11), uint64(1<<32-41)))))
+		Expect(old.Delta(newer, WithCounterWrap(0))).To(HaveExactElements(
+			HaveField("Counters", HaveExactElements(^uint64(0)-4294967284, ^uint64(0)-40))))
+		Expect(old.Delta(newer, WithCounterWrap(100))).To(HaveExactElements(
+			HaveField("Counters", HaveExactElements(uint64(0), uint64(59)))))
+	})
+
+	It("computes deltas between captured files", func() {
+		for _, before := range []string{
+			"./testdata/mixed/proc/interrupts",
+			"./testdata/snapshots/interrupts.gz",
+		} {
+			deltas, err := DeltaFiles(before, "./testdata/snapshots/interrupts-after")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveExactElements(
+				IRQ{Num: 0, CPUs: CPUList{0, 1, 2}, Counters: []uint64{4, 1, 0}},
+				IRQ{Num: 1, CPUs: CPUList{0, 1, 2}, Counters: []uint64{0, 0, 1}},
+				IRQ{Num: 42, CPUs: CPUList{0, 1, 2}, Counters: []uint64{10, 66, 0}},
+				IRQ{Num: 43, CPUs: CPUList{0, 1, 2}, Counters: []uint64{34, 0, 5}},
+				IRQ{Num: 44, CPUs: CPUList{0, 1, 2}, Counters: []uint64{1, 2, 3}}), "before %s", before)
+		}
+	})
+
+	It("reports unusable captured files", func() {
+		_, err := DeltaFiles("./testdata/non-existing", "./testdata/snapshots/interrupts-after")
+		Expect(err).To(HaveOccurred())
+		_, err = DeltaFiles("./testdata/snapshots/interrupts-after", "./testdata/non-existing")
+		Expect(err).To(HaveOccurred())
+		path := GinkgoT().TempDir() + "/empty"
+		Expect(os.WriteFile(path, nil, 0o644)).To(Succeed())
+		_, err = DeltaFiles(path, "./testdata/snapshots/interrupts-after")
+		Expect(err).To(MatchError(ContainSubstring("no IRQ counters")))
+	})
+
+	It("computes rates", func() {
+		old := snapshotOf(allCounters(strings.NewReader(procInterruptsText), nil))
+		old.Time = time.Unix(1000, 0)
+		newer := snapshotOf(allCounters(strings.NewReader(` CPU1 CPU42 CPU666
+ 1: 12 3 5 x
+`), nil))
+		newer.Time = old.Time.Add(2 * time.Second)
+		Expect(old.Rate(newer)).To(HaveExactElements(
+			IRQRate{
+				Num:   1,
+				CPUs:  CPUList{1, 42, 666},
+				Rates: []float64{5, 0, 0.5},
+			}))
+
+		_, err := newer.Rate(old)
+		Expect(err).To(MatchError(ContainSubstring("must be positive")))
+		_, err = old.Rate(old)
+		Expect(err).To(HaveOccurred())
+		_, err = Snapshot{}.Rate(newer)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("takes a snapshot of the system", func() {
+		snap := TakeSnapshot()
+		Expect(snap.Time).NotTo(BeZero())
+		Expect(snap.CPUs).NotTo(BeEmpty())
+		Expect(snap.IRQs).NotTo(BeEmpty())
+	})
+
+})
@@ -0,0 +1,77 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"io"
+	"iter"
+)
+
+const procSoftIRQsPath = "/proc/softirqs"
+
+// SoftIRQ holds the per-CPU counters for a particular kind of software
+// interrupt, such as “NET_RX” or “TIMER”. Similar to [IRQ], the counters are
+// valid only for the duration of the yield call producing this soft IRQ data
+// and will be reused/overwritten afterwards.
+type SoftIRQ struct {
+	Name     string   // soft IRQ name, such as "NET_RX".
+	Counters []uint64 // per-CPU counters, valid during a single iteration, then reused.
+	CPUs     CPUList  // list of the number of the CPUs that are currently online.
+}
+
+// AllSoftIRQs returns a single-use iterator that loops over “/proc/softirqs”,
+// producing the per-CPU counters of the individual kinds of software
+// interrupts, but only for CPUs that are currently online.
+func AllSoftIRQs() iter.Seq[SoftIRQ] {
+	return AllSoftIRQsFrom("")
+}
+
+// AllSoftIRQsFrom returns a single-use iterator that loops over
+// “/proc/softirqs” located beneath the specified root, producing the per-CPU
+// counters of the individual kinds of software interrupts. An empty root
+// refers to the root of the file system, so this then is the same as
+// [AllSoftIRQs].
+func AllSoftIRQsFrom(root string) iter.Seq[SoftIRQ] {
+	return func(yield func(SoftIRQ) bool) {
+		f, err := openProcFile(root, procSoftIRQsPath)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		iterateSoftIRQs(f, yield)
+	}
+}
+
+// allSoftIRQs returns an iterator looping over the software interrupts with
+// their per-CPU counters based on the information in “/proc/softirqs” format
+// and produced by the specified reader.
+func allSoftIRQs(r io.Reader) iter.Seq[SoftIRQ] {
+	return func(yield func(SoftIRQ) bool) {
+		iterateSoftIRQs(r, yield)
+	}
+}
+
+// iterateSoftIRQs reuses the named interrupt parsing, as “/proc/softirqs”
+// shares the header line of CPUs with “/proc/interrupts”, followed only by
+// named rows.
+func iterateSoftIRQs(r io.Reader, yield func(SoftIRQ) bool) {
+	iterateNamedCounters(r, func(named NamedInterrupt) bool {
+		return yield(SoftIRQ{
+			Name:     named.Name,
+			Counters: named.Counters,
+			CPUs:     named.CPUs,
+		})
+	})
+}
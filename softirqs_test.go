@@ -0,0 +1,87 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("soft IRQs", func() {
+
+	It("yields nothing for invalid data", func() {
+		Expect(allSoftIRQs(strings.NewReader(""))).To(BeEmpty())
+		Expect(allSoftIRQs(strings.NewReader(" FOO1\n HI: 1"))).To(BeEmpty())
+	})
+
+	It("yields soft IRQs", func() {
+		f := Successful(os.Open("./testdata/mixed/proc/softirqs"))
+		defer f.Close()
+		softirqs := []SoftIRQ{}
+		for softirq := range allSoftIRQs(f) {
+			softirq.Counters = slices.Clone(softirq.Counters)
+			softirqs = append(softirqs, softirq)
+		}
+		Expect(softirqs).To(HaveLen(10))
+		Expect(softirqs[0]).To(Equal(SoftIRQ{
+			Name:     "HI",
+			Counters: []uint64{0, 1, 0, 2},
+			CPUs:     CPUList{0, 1, 2, 3},
+		}))
+		Expect(softirqs[3]).To(And(
+			HaveField("Name", "NET_RX"),
+			HaveField("Counters", HaveExactElements(
+				uint64(123), uint64(456), uint64(789), uint64(1011)))))
+		Expect(softirqs[9]).To(HaveField("Name", "RCU"))
+	})
+
+	It("stops the yield when told", func() {
+		f := Successful(os.Open("./testdata/mixed/proc/softirqs"))
+		defer f.Close()
+		items := 0
+		for range allSoftIRQs(f) {
+			items++
+			break
+		}
+		Expect(items).To(Equal(1))
+	})
+
+	It("reads from /proc/softirqs beneath a root", func() {
+		names := []string{}
+		for softirq := range AllSoftIRQsFrom("./testdata/mixed") {
+			names = append(names, softirq.Name)
+		}
+		Expect(names).To(HaveExactElements(
+			"HI", "TIMER", "NET_TX", "NET_RX", "BLOCK", "IRQ_POLL", "TASKLET", "SCHED", "HRTIMER", "RCU"))
+		Expect(AllSoftIRQsFrom("./testdata/non-existing")).To(BeEmpty())
+		Expect(AllSoftIRQsFrom("./testdata/arm64")).To(BeEmpty())
+	})
+
+	It("reads something sensible from /proc/softirqs", func() {
+		items := 0
+		for softirq := range AllSoftIRQs() {
+			items++
+			Expect(softirq.Name).NotTo(BeEmpty())
+			Expect(softirq.Counters).To(HaveLen(len(softirq.CPUs)))
+		}
+		Expect(items).NotTo(BeZero())
+	})
+
+})
@@ -0,0 +1,163 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Watch samples the per-CPU counters of all (non-architecture-specific) IRQs
+// every interval and then calls fn with the per-CPU counter deltas since the
+// previous sample, that is, the number of interrupts per interval. Watch
+// blocks until the passed context gets cancelled, returning the context's
+// error. Watch returns early with an error if the interval isn't positive or
+// there are no IRQ counters available at all, in particular
+// [ErrUnsupportedPlatform] when not running on Linux.
+//
+// Samples that fail to read any IRQ counters are skipped, so the next deltas
+// are again relative to the last successful sample.
+//
+// The IRQs passed to fn own their counters and thus can be safely retained.
+func Watch(ctx context.Context, interval time.Duration, fn func([]IRQ)) error {
+	if err := supportedPlatform(""); err != nil {
+		return err
+	}
+	return watch(ctx, interval, TakeSnapshot, fn)
+}
+
+// watch implements Watch with the specified function for taking snapshots.
+func watch(ctx context.Context, interval time.Duration, take func() Snapshot, fn func([]IRQ)) error {
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+	old := take()
+	if len(old.IRQs) == 0 {
+		return errors.New("no IRQ counters available")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// Both a tick and the cancellation might be ready at the same
+			// time, with select then picking randomly; so better check again.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// An empty snapshot indicates that reading the counters failed
+			// this time, so we skip it instead of reporting bogus deltas and
+			// then diffing against it in the next round.
+			newer := take()
+			if len(newer.IRQs) == 0 {
+				continue
+			}
+			fn(old.Delta(newer))
+			old = newer
+		}
+	}
+}
+
+// WatchDetails walks the details of all (non-architecture-specific) IRQs every
+// interval and then calls fn with only those IRQs whose (effective) affinities
+// or actions changed since the previous walk, such as when irqbalance moved an
+// IRQ to other CPUs, in ascending order of IRQ numbers. IRQs that newly
+// appeared count as changed, while IRQs that vanished are not reported. If
+// nothing changed, fn doesn't get called. WatchDetails blocks until the passed
+// context gets cancelled, returning the context's error. WatchDetails returns
+// early with an error if the interval isn't positive or there are no IRQ
+// details available at all, in particular [ErrUnsupportedPlatform] when not
+// running on Linux.
+//
+// Please note that each walk reads several pseudo files per IRQ, please see
+// [AllIRQDetails], so on systems with many IRQs intervals should be chosen
+// generously, in the range of seconds rather than milliseconds.
+func WatchDetails(ctx context.Context, interval time.Duration, fn func(changed []IRQDetails)) error {
+	if err := supportedPlatform(""); err != nil {
+		return err
+	}
+	return watchDetails(ctx, interval, AllIRQDetailsContext, fn)
+}
+
+// watchDetails implements WatchDetails with the specified function for walking
+// the IRQ details.
+func watchDetails(
+	ctx context.Context,
+	interval time.Duration,
+	walk func(context.Context) iter.Seq[IRQDetails],
+	fn func([]IRQDetails),
+) error {
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+	old := maps.Collect(detailsByNum(walk(ctx)))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(old) == 0 {
+		return errors.New("no IRQ details available")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			newer := maps.Collect(detailsByNum(walk(ctx)))
+			// Don't diff against an incomplete walk in case the context got
+			// cancelled in the middle of it.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var changed []IRQDetails
+			for num, details := range newer {
+				if prev, ok := old[num]; ok &&
+					prev.Actions == details.Actions &&
+					EqualAffinities(prev.Affinities, details.Affinities) {
+					continue
+				}
+				changed = append(changed, details)
+			}
+			old = newer
+			if len(changed) == 0 {
+				continue
+			}
+			slices.SortFunc(changed, func(a, b IRQDetails) int {
+				return cmp.Compare(a.Num, b.Num)
+			})
+			fn(changed)
+		}
+	}
+}
+
+// detailsByNum returns an iterator producing the IRQ details from the
+// specified iterator keyed by their IRQ numbers.
+func detailsByNum(it iter.Seq[IRQDetails]) iter.Seq2[uint, IRQDetails] {
+	return func(yield func(uint, IRQDetails) bool) {
+		for details := range it {
+			if !yield(details.Num, details) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package irks
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/thediveo/cpus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("watching IRQ counters", func() {
+
+	It("rejects invalid intervals", func() {
+		Expect(Watch(context.Background(), 0, func([]IRQ) {})).To(
+			MatchError(ContainSubstring("must be positive")))
+	})
+
+	It("rejects missing counters", func() {
+		Expect(watch(context.Background(), time.Millisecond,
+			func() Snapshot { return Snapshot{} },
+			func([]IRQ) {})).To(MatchError(ContainSubstring("no IRQ counters")))
+	})
+
+	It("reports deltas until cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		count := uint64(0)
+		take := func() Snapshot {
+			count += 10
+			return Snapshot{
+				CPUs: CPUList{0},
+				IRQs: []IRQ{{Num: 42, CPUs: CPUList{0}, Counters: []uint64{count}}},
+			}
+		}
+		calls := 0
+		Expect(watch(ctx, time.Millisecond, take, func(irqs []IRQ) {
+			Expect(irqs).To(HaveExactElements(
+				And(HaveField("Num", uint(42)),
+					HaveField("Counters", HaveExactElements(uint64(10))))))
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+		})).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("skips empty samples", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		takes := 0
+		take := func() Snapshot {
+			takes++
+			if takes%2 == 0 {
+				return Snapshot{}
+			}
+			return Snapshot{
+				CPUs: CPUList{0},
+				IRQs: []IRQ{{Num: 42, CPUs: CPUList{0}, Counters: []uint64{uint64(takes * 10)}}},
+			}
+		}
+		calls := 0
+		Expect(watch(ctx, time.Millisecond, take, func(irqs []IRQ) {
+			Expect(irqs).To(HaveExactElements(
+				HaveField("Counters", HaveExactElements(uint64(20)))))
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+		})).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("doesn't sample after being cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		takes := 0
+		take := func() Snapshot {
+			takes++
+			return Snapshot{
+				CPUs: CPUList{0},
+				IRQs: []IRQ{{Num: 42, CPUs: CPUList{0}, Counters: []uint64{uint64(takes)}}},
+			}
+		}
+		Expect(watch(ctx, time.Millisecond, take, func([]IRQ) {
+			cancel()
+			// make sure the next tick is ready alongside the cancellation.
+			time.Sleep(5 * time.Millisecond)
+		})).To(MatchError(context.Canceled))
+		Expect(takes).To(Equal(2))
+	})
+
+	It("watches real IRQ counters", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(Watch(ctx, 10*time.Millisecond, func(irqs []IRQ) {
+			Expect(irqs).NotTo(BeEmpty())
+			cancel()
+		})).To(MatchError(context.Canceled))
+	})
+
+})
+
+var _ = Describe("watching IRQ details", func() {
+
+	It("rejects invalid intervals", func() {
+		Expect(WatchDetails(context.Background(), 0, func([]IRQDetails) {})).To(
+			MatchError(ContainSubstring("must be positive")))
+	})
+
+	It("rejects missing details", func() {
+		Expect(watchDetails(context.Background(), time.Millisecond,
+			func(context.Context) iter.Seq[IRQDetails] { return slices.Values([]IRQDetails{}) },
+			func([]IRQDetails) {})).To(MatchError(ContainSubstring("no IRQ details")))
+	})
+
+	It("reports only changed details until cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		walks := [][]IRQDetails{
+			{
+				{Num: 1, Actions: "foo", Affinities: cpus.List{{0, 1}}},
+				{Num: 2, Actions: "bar", Affinities: cpus.List{{2, 2}}},
+			},
+			// nothing changed, albeit differently expressed.
+			{
+				{Num: 2, Actions: "bar", Affinities: cpus.List{{2, 2}}},
+				{Num: 1, Actions: "foo", Affinities: cpus.List{{0, 0}, {1, 1}}},
+			},
+			// affinity and actions changed, new IRQ, vanished IRQ.
+			{
+				{Num: 3, Actions: "baz", Affinities: cpus.List{{0, 0}}},
+				{Num: 2, Actions: "bar,baz", Affinities: cpus.List{{2, 2}}},
+				{Num: 1, Actions: "foo", Affinities: cpus.List{{1, 1}}},
+			},
+			{
+				{Num: 1, Actions: "foo", Affinities: cpus.List{{1, 1}}},
+				{Num: 2, Actions: "bar,baz", Affinities: cpus.List{{2, 2}}},
+			},
+		}
+		walk := func(context.Context) iter.Seq[IRQDetails] {
+			details := walks[0]
+			walks = walks[1:]
+			if len(walks) == 0 {
+				cancel()
+			}
+			return slices.Values(details)
+		}
+		calls := 0
+		Expect(watchDetails(ctx, time.Millisecond, walk, func(changed []IRQDetails) {
+			calls++
+			Expect(changed).To(HaveExactElements(
+				HaveField("Num", uint(1)),
+				HaveField("Num", uint(2)),
+				HaveField("Num", uint(3))))
+		})).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("doesn't diff incomplete walks", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		walks := 0
+		walk := func(context.Context) iter.Seq[IRQDetails] {
+			walks++
+			if walks > 1 {
+				cancel()
+				return slices.Values([]IRQDetails{})
+			}
+			return slices.Values([]IRQDetails{{Num: 1}})
+		}
+		Expect(watchDetails(ctx, time.Millisecond, walk, func([]IRQDetails) {
+			Fail("unexpected change callback")
+		})).To(MatchError(context.Canceled))
+	})
+
+	It("returns the context's error when cancelled during the first walk", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		walk := func(context.Context) iter.Seq[IRQDetails] {
+			cancel()
+			return slices.Values([]IRQDetails{})
+		}
+		Expect(watchDetails(ctx, time.Millisecond, walk, func([]IRQDetails) {
+			Fail("unexpected change callback")
+		})).To(MatchError(context.Canceled))
+	})
+
+	It("watches real IRQ details", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		walks := 0
+		walk := func(ctx context.Context) iter.Seq[IRQDetails] {
+			walks++
+			if walks == 2 {
+				cancel()
+			}
+			return AllIRQDetailsContext(ctx)
+		}
+		Expect(watchDetails(ctx, 10*time.Millisecond, walk, func(changed []IRQDetails) {
+			Expect(changed).NotTo(BeEmpty())
+		})).To(MatchError(context.Canceled))
+		Expect(walks).To(Equal(2))
+		Expect(WatchDetails(ctx, 10*time.Millisecond, func([]IRQDetails) {})).To(
+			MatchError(context.Canceled))
+	})
+
+})